@@ -0,0 +1,53 @@
+package main
+
+import (
+	"path"
+	"strings"
+)
+
+const (
+	botsExclude  = "exclude"
+	botsSeparate = "separate"
+	botsKeep     = "keep"
+)
+
+// botFilter decides which contributors are bots and automation accounts.
+// Logins ending in [bot] and authors GitHub reports as apps are detected
+// automatically; the exclude patterns add more logins and the include
+// patterns exempt logins from detection.
+type botFilter struct {
+	include []string
+	exclude []string
+}
+
+func (f botFilter) isBot(r contributorRow) bool {
+	login := strings.ToLower(r.Contributor)
+	if matchAny(f.include, login) {
+		return false
+	}
+	return r.Bot || strings.HasSuffix(login, "[bot]") || matchAny(f.exclude, login)
+}
+
+// split separates the rows of bots from those of people.
+func (f botFilter) split(rows []contributorRow) (people, bots []contributorRow) {
+	for _, r := range rows {
+		if f.isBot(r) {
+			r.Bot = true
+			bots = append(bots, r)
+		} else {
+			people = append(people, r)
+		}
+	}
+	return people, bots
+}
+
+// matchAny reports whether login matches any of the glob patterns, compared
+// case-insensitively.
+func matchAny(patterns []string, login string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToLower(pattern), login); ok {
+			return true
+		}
+	}
+	return false
+}
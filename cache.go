@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// cacheEntry is a cached API response, stored as JSON in the cache
+// directory.
+type cacheEntry struct {
+	URL     string      `json:"url"`
+	Header  http.Header `json:"header"`
+	Body    []byte      `json:"body"`
+	Stored  time.Time   `json:"stored"`
+	Fetched time.Time   `json:"fetched"`
+}
+
+// cacheTransport keeps successful GET responses on disk and revalidates
+// them with If-None-Match and If-Modified-Since, so that unchanged data
+// costs a 304 response, which does not count against the rate limit.
+// Entries are keyed by URL and a hash of the Authorization header, so that
+// tokens with different access never share responses.
+type cacheTransport struct {
+	dir  string
+	base http.RoundTripper
+	// refresh ignores cached entries, but still stores the responses.
+	refresh bool
+}
+
+// defaultCacheDir returns the directory responses are cached in.
+func defaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "ghstats"), nil
+}
+
+// newClient returns the HTTP client for the GitHub API, which caches
+// responses on disk unless -no-cache is given.
+func newClient(opts options) *http.Client {
+	if opts.noCache {
+		return &http.Client{}
+	}
+	dir, err := defaultCacheDir()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Not caching responses: %v\n", err)
+		return &http.Client{}
+	}
+	return &http.Client{Transport: &cacheTransport{dir: dir, base: http.DefaultTransport, refresh: opts.refresh}}
+}
+
+func (t *cacheTransport) path(req *http.Request) string {
+	token := sha256.Sum256([]byte(req.Header.Get("Authorization")))
+	key := sha256.Sum256([]byte(req.URL.String() + "\n" + hex.EncodeToString(token[:])))
+	return filepath.Join(t.dir, hex.EncodeToString(key[:])+".json")
+}
+
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.base.RoundTrip(req)
+	}
+
+	path := t.path(req)
+	var cached *cacheEntry
+	if !t.refresh {
+		cached, _ = readCacheEntry(path)
+	}
+	if cached != nil {
+		req = req.Clone(req.Context())
+		if etag := cached.Header.Get("ETag"); etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if modified := cached.Header.Get("Last-Modified"); modified != "" {
+			req.Header.Set("If-Modified-Since", modified)
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		resp.Body.Close()
+		cached.Fetched = time.Now()
+		// The cache is best effort: failing to write it only costs a
+		// full response next time.
+		_ = writeCacheEntry(path, cached)
+		return cached.response(req), nil
+	}
+	if resp.StatusCode != http.StatusOK ||
+		(resp.Header.Get("ETag") == "" && resp.Header.Get("Last-Modified") == "") {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	entry := &cacheEntry{URL: req.URL.String(), Header: resp.Header, Body: body, Stored: now, Fetched: now}
+	_ = writeCacheEntry(path, entry)
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+// response rebuilds the cached response to req.
+func (e *cacheEntry) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+func readCacheEntry(path string) (*cacheEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// writeCacheEntry writes the entry to a temporary file first, so that an
+// interrupted run never leaves a truncated entry behind.
+func writeCacheEntry(path string, entry *cacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".entry-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// runCache implements the cache subcommand:
+//
+//	ghstats cache list
+//	ghstats cache prune [-older-than DURATION]
+func runCache(args []string) error {
+	dir, err := defaultCacheDir()
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("usage: ghstats cache list|prune [-older-than DURATION]")
+	}
+
+	switch args[0] {
+	case "list":
+		entries, err := listCache(dir)
+		if err != nil {
+			return err
+		}
+		var size int
+		for _, e := range entries {
+			fmt.Printf("%s  %8d  %s\n", e.entry.Fetched.Format(time.DateTime), len(e.entry.Body), e.entry.URL)
+			size += len(e.entry.Body)
+		}
+		fmt.Printf("%d entries, %d bytes in %s\n", len(entries), size, dir)
+		return nil
+	case "prune":
+		flags := flag.NewFlagSet("cache prune", flag.ContinueOnError)
+		olderThan := flags.Duration("older-than", 30*24*time.Hour, "remove entries not used for this `duration`; 0 removes all")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		entries, err := listCache(dir)
+		if err != nil {
+			return err
+		}
+		removed := 0
+		for _, e := range entries {
+			if time.Since(e.entry.Fetched) >= *olderThan {
+				if err := os.Remove(e.path); err != nil {
+					return err
+				}
+				removed++
+			}
+		}
+		fmt.Printf("Removed %d of %d entries from %s\n", removed, len(entries), dir)
+		return nil
+	default:
+		return fmt.Errorf("unknown cache command %q: want list or prune", args[0])
+	}
+}
+
+type cacheFile struct {
+	path  string
+	entry *cacheEntry
+}
+
+// listCache reads every entry in the cache directory, oldest first.
+// Unreadable entries are skipped.
+func listCache(dir string) ([]cacheFile, error) {
+	files, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []cacheFile
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(dir, f.Name())
+		entry, err := readCacheEntry(path)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, cacheFile{path, entry})
+	}
+	slices.SortFunc(entries, func(a, b cacheFile) int { return a.entry.Fetched.Compare(b.entry.Fetched) })
+	return entries, nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// checkpoint is the state of a run, saved next to the output file after
+// every repository so that an interrupted run can be continued with -resume.
+type checkpoint struct {
+	Start time.Time
+	End   time.Time
+	Repos []string
+	// Flags are the command line flags that affect the results.
+	Flags map[string]string
+	// Completed is the number of repositories processed, in order. Results
+	// can be shorter, as repositories that could not be processed have none.
+	Completed int
+	Results   []repoResult
+}
+
+// checkpointPath returns the checkpoint file of an output file, e.g.
+// output.checkpoint.json for output.csv.
+func checkpointPath(out string) string {
+	return strings.TrimSuffix(out, filepath.Ext(out)) + ".checkpoint.json"
+}
+
+// resultFlags returns the flags given on the command line, except those that
+// do not change the results.
+func resultFlags() map[string]string {
+	flags := make(map[string]string)
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "resume", "no-cache", "refresh", "archive", "no-archive":
+		default:
+			flags[f.Name] = f.Value.String()
+		}
+	})
+	return flags
+}
+
+// sameRun reports whether c was saved by a run with the same range,
+// repositories and flags as other.
+func (c checkpoint) sameRun(other checkpoint) bool {
+	return c.Start.Equal(other.Start) && c.End.Equal(other.End) &&
+		slices.Equal(c.Repos, other.Repos) && maps.Equal(c.Flags, other.Flags)
+}
+
+func loadCheckpoint(path string) (checkpoint, error) {
+	var c checkpoint
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(data, &c)
+	return c, err
+}
+
+// save writes the checkpoint through a temporary file, so that an
+// interrupted write leaves the previous checkpoint intact.
+func (c checkpoint) save(path string) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
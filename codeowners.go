@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// unowned collects the lines changed in files no CODEOWNERS rule covers.
+const unowned = "(unowned)"
+
+// codeOwners holds the rules of a CODEOWNERS file in file order.
+type codeOwners []codeOwnersRule
+
+type codeOwnersRule struct {
+	pattern string
+	owners  []string
+}
+
+// codeOwnersLocations are the places GitHub looks for a CODEOWNERS file, in
+// the order it looks.
+var codeOwnersLocations = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// fetchCodeOwners reads the CODEOWNERS file of a repository's default
+// branch. It returns no rules when the repository has none.
+func fetchCodeOwners(client *http.Client, owner, repo, token string) (codeOwners, error) {
+	for _, location := range codeOwnersLocations {
+		data, found, err := fetchFileContent(client, owner, repo, location, token)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			return parseCodeOwners(data), nil
+		}
+	}
+	return nil, nil
+}
+
+func parseCodeOwners(data string) codeOwners {
+	var rules codeOwners
+	for _, line := range strings.Split(data, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		rules = append(rules, codeOwnersRule{pattern: fields[0], owners: fields[1:]})
+	}
+	return rules
+}
+
+// ownersOf returns the owners of a path. As on GitHub, the last matching
+// rule wins, and a matching rule without owners leaves the path unowned.
+func (c codeOwners) ownersOf(path string) []string {
+	for i := len(c) - 1; i >= 0; i-- {
+		if matchPath(c[i].pattern, path) {
+			if len(c[i].owners) == 0 {
+				break
+			}
+			return c[i].owners
+		}
+	}
+	return []string{unowned}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// repoData is everything fetched for one repository. Endpoints that failed
+// leave their ok flag unset, so their metrics stay empty.
+type repoData struct {
+	repo string
+	// err is the transient failure that stopped fetching, see failed.
+	err error
+
+	stats   []ContributorStats
+	commits []Commit
+	files   repoFiles
+
+	prs          []PullRequest
+	pullsOK      bool
+	reviews      map[int][]Review
+	comments     []ReviewComment
+	reviewsOK    bool
+	deploys      []time.Time
+	deploySource string
+	firstCommits map[int]time.Time
+	doraOK       bool
+
+	issues        []Issue
+	issueComments []IssueComment
+	issuesOK      bool
+
+	frequency     [][3]int64
+	activity      []CommitActivity
+	participation Participation
+	punchCard     [][3]int
+	repoStatsOK   bool
+}
+
+// collectRepo fetches everything the report needs for one repository and
+// joins it into contributor rows. Failures of individual endpoints are
+// reported and leave the corresponding metrics empty, except transient ones
+// such as rate limits, which are returned so that the repository can be
+// fetched again. With -compare, the data is fetched once for both periods
+// and the baseline is summed up from it as well.
+func collectRepo(client *http.Client, owner, repoName, token string, start, end time.Time, opts options) (repoResult, error) {
+	from, to := start, end
+	if opts.compare {
+		if opts.baselineStart.Before(from) {
+			from = opts.baselineStart
+		}
+		if opts.baselineEnd.After(to) {
+			to = opts.baselineEnd
+		}
+	}
+
+	data := fetchRepoData(client, owner, repoName, token, from, to, opts)
+	if data.err != nil {
+		return repoResult{}, data.err
+	}
+	result := data.result(start, end, opts)
+	if opts.compare {
+		baseline := data.result(opts.baselineStart, opts.baselineEnd, opts)
+		result.Baseline = &baseline
+	}
+	return result, nil
+}
+
+// failed reports that fetching what failed. A transient failure is kept in
+// d.err instead, and stops fetching.
+func (d *repoData) failed(what string, err error) bool {
+	if transient(err) {
+		d.err = fmt.Errorf("fetching %s for %s: %w", what, d.repo, err)
+		return true
+	}
+	fmt.Fprintf(os.Stderr, "Error fetching %s for %s: %v\n", what, d.repo, err)
+	return false
+}
+
+func fetchRepoData(client *http.Client, owner, repoName, token string, start, end time.Time, opts options) repoData {
+	repo := owner + "/" + repoName
+	data := repoData{repo: repo}
+
+	var err error
+	if opts.source == sourceCommits {
+		data.commits, err = fetchCommits(client, owner, repoName, token, start, end)
+		if err != nil && data.failed("commits", err) {
+			return data
+		}
+		data.files = repoFiles{filter: opts.pathFilter}
+		if opts.codeOwners {
+			data.files.owners, err = fetchCodeOwners(client, owner, repoName, token)
+			if err != nil && data.failed("CODEOWNERS", err) {
+				return data
+			}
+		}
+		if opts.gitAttributes {
+			data.files.filter.attributes, err = fetchGitAttributes(client, owner, repoName, token)
+			if err != nil && data.failed(".gitattributes", err) {
+				return data
+			}
+		}
+	} else {
+		data.stats, err = fetchContributorStats(client, owner, repoName, token)
+		if err != nil && data.failed("stats", err) {
+			return data
+		}
+	}
+
+	if opts.prs || opts.reviews || opts.dora {
+		// Reviews need every pull request updated since the start, which
+		// also has those of the other metrics. Otherwise only the pull
+		// requests created or closed within the range are searched for.
+		if opts.reviews {
+			data.prs, err = fetchPullRequests(client, owner, repoName, token, start)
+		} else {
+			data.prs, err = searchPullRequests(client, owner, repoName, token, start, end, !opts.prs)
+			if errors.Is(err, errSearchLimit) {
+				data.prs, err = fetchPullRequests(client, owner, repoName, token, start)
+			}
+		}
+		if err == nil && opts.prs {
+			err = fetchPullRequestSizes(client, owner, repoName, token, data.prs, start, end)
+		}
+		if err != nil {
+			if data.failed("pull requests", err) {
+				return data
+			}
+		} else {
+			data.pullsOK = opts.prs
+
+			if opts.reviews {
+				data.reviews, err = fetchReviews(client, owner, repoName, token, data.prs)
+				if err == nil {
+					data.comments, err = fetchReviewComments(client, owner, repoName, token, start)
+				}
+				if err != nil {
+					if data.failed("reviews", err) {
+						return data
+					}
+				} else {
+					data.reviewsOK = true
+				}
+			}
+
+			if opts.dora {
+				data.deploys, data.deploySource, err = fetchDeployTimes(client, owner, repoName, token, opts.doraEnvironment, start)
+				if err == nil {
+					data.firstCommits, err = fetchFirstCommitTimes(client, owner, repoName, token, data.prs, start, end)
+				}
+				if err != nil {
+					if data.failed("deployments", err) {
+						return data
+					}
+				} else {
+					data.doraOK = true
+				}
+			}
+		}
+	}
+
+	if opts.issues {
+		data.issues, err = fetchIssues(client, owner, repoName, token, start, end)
+		if err == nil {
+			data.issueComments, err = fetchIssueComments(client, owner, repoName, token, start)
+		}
+		if err != nil {
+			if data.failed("issues", err) {
+				return data
+			}
+		} else {
+			data.issuesOK = true
+		}
+	}
+
+	if opts.repoStats {
+		data.frequency, data.activity, data.participation, data.punchCard, err = fetchRepoStats(client, owner, repoName, token)
+		if err != nil {
+			if data.failed("repository stats", err) {
+				return data
+			}
+		} else {
+			data.repoStatsOK = true
+		}
+	}
+	return data
+}
+
+// result sums up the data fetched for a repository within the range.
+func (d repoData) result(start, end time.Time, opts options) repoResult {
+	result := repoResult{Repository: d.repo}
+
+	var rows *repoRows
+	if opts.source == sourceCommits {
+		rows = newRepoRows(d.repo, processCommits(d.commits, d.repo, d.files, start, end, opts))
+	} else {
+		rows = newRepoRows(d.repo, processStats(d.stats, d.repo, start, end))
+	}
+
+	if d.pullsOK {
+		joinPullRequests(rows, processPullRequests(d.prs, start, end))
+	}
+	if d.reviewsOK {
+		joinReviews(rows, processReviews(d.prs, d.reviews, d.comments, start, end))
+	}
+	if d.doraOK {
+		result.DORA = processDORA(d.prs, d.firstCommits, d.deploys, d.deploySource, start, end)
+	}
+	if d.issuesOK {
+		metrics, summary := processIssues(d.issues, d.issueComments, start, end)
+		joinIssues(rows, metrics)
+		result.Issues = summary
+	}
+	if d.repoStatsOK {
+		result.Stats = processRepoStats(d.frequency, d.activity, d.participation, d.punchCard, start, end, time.Now())
+	}
+
+	result.Rows = rows.rows()
+	for _, r := range result.Rows {
+		result.Commits += r.Commits
+	}
+	for i := range result.Rows {
+		result.Rows[i].Start, result.Rows[i].End = start, end
+		result.Rows[i].RepoCommits = result.Commits
+	}
+	if opts.bots != botsKeep {
+		result.Rows, result.Bots = opts.botFilter.split(result.Rows)
+		if opts.bots == botsExclude {
+			result.Bots = nil
+		}
+	}
+	if opts.identities != nil {
+		result.Rows = opts.identities.merge(result.Rows)
+		result.Bots = opts.identities.merge(result.Bots)
+	}
+	return result
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+type Commit struct {
+	SHA    string `json:"sha"`
+	Commit struct {
+		Author struct {
+			Name  string    `json:"name"`
+			Email string    `json:"email"`
+			Date  time.Time `json:"date"`
+		} `json:"author"`
+		Message string `json:"message"`
+	} `json:"commit"`
+	// Author is nil when the commit email is not linked to a GitHub
+	// account.
+	Author *struct {
+		Login string `json:"login"`
+		Type  string `json:"type"`
+	} `json:"author"`
+	Parents []struct {
+		SHA string `json:"sha"`
+	} `json:"parents"`
+	Stats struct {
+		Additions int `json:"additions"`
+		Deletions int `json:"deletions"`
+	} `json:"stats"`
+	Files []CommitFile `json:"files"`
+}
+
+type CommitFile struct {
+	Filename  string `json:"filename"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+}
+
+// fetchCommits lists the commits on the default branch of a repository
+// authored within the range, and fetches each of them individually for
+// their line counts and files. Merge commits are left out, as their changes
+// are already counted in the commits they merge.
+func fetchCommits(client *http.Client, owner, repo, token string, start, end time.Time) ([]Commit, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/commits?per_page=100&since=%s&until=%s",
+		githubAPI, owner, repo, start.UTC().Format(time.RFC3339), end.UTC().Format(time.RFC3339))
+	var commits []Commit
+	for url != "" {
+		var page []Commit
+		next, err := getJSON(client, url, token, &page)
+		if err != nil {
+			return nil, err
+		}
+		for _, commit := range page {
+			if len(commit.Parents) <= 1 {
+				commits = append(commits, commit)
+			}
+		}
+		url = next
+	}
+
+	for i := range commits {
+		url := fmt.Sprintf("%s/repos/%s/%s/commits/%s", githubAPI, owner, repo, commits[i].SHA)
+		if _, err := getJSON(client, url, token, &commits[i]); err != nil {
+			return nil, err
+		}
+	}
+	return commits, nil
+}
+
+// contributor returns who a commit is attributed to: the login of its
+// author or, for authors without a GitHub account, their commit name and
+// email.
+func (c Commit) contributor() string {
+	email := strings.ToLower(c.Commit.Author.Email)
+	switch {
+	case c.Author != nil:
+		return c.Author.Login
+	case email == "":
+		return unlinkedContributor
+	default:
+		return fmt.Sprintf("%s <%s>", c.Commit.Author.Name, email)
+	}
+}
+
+// repoFiles holds what is known about the files of a repository when
+// commits are counted file by file.
+type repoFiles struct {
+	owners codeOwners
+	filter pathFilter
+}
+
+// processCommits sums up commits per contributor. Only the lines changed in
+// files the path filter keeps are counted, and they are broken down by the
+// owners of each file with -codeowners and by language with -languages.
+// Ignored commits are not counted, but tallied separately. Commits authored
+// outside the range are skipped.
+func processCommits(commits []Commit, repo string, files repoFiles, start, end time.Time, opts options) []contributorRow {
+	rows := newRepoRows(repo, nil)
+	for _, commit := range commits {
+		if !inRange(commit.Commit.Author.Date, start, end) {
+			continue
+		}
+		r := rows.get(commit.contributor())
+		if commit.Author != nil && commit.Author.Type == "Bot" {
+			r.Bot = true
+		}
+		email := strings.ToLower(commit.Commit.Author.Email)
+		if email != "" && !slices.Contains(r.Emails, email) {
+			r.Emails = append(r.Emails, email)
+		}
+
+		kept := commit.Files
+		lines := lineCounts{commit.Stats.Additions, commit.Stats.Deletions}
+		if !files.filter.empty() {
+			kept, lines = nil, lineCounts{}
+			for _, file := range commit.Files {
+				if files.filter.keep(file.Filename) {
+					kept = append(kept, file)
+					lines.Additions += file.Additions
+					lines.Deletions += file.Deletions
+				}
+			}
+		}
+
+		if opts.commitIgnore.ignores(commit) {
+			r.ExcludedCommits++
+			r.ExcludedLines += lines.Additions + lines.Deletions
+			continue
+		}
+		r.Commits++
+		r.Additions += lines.Additions
+		r.Deletions += lines.Deletions
+		r.Weeks = r.Weeks.add(weekOf(commit.Commit.Author.Date), weekCounts{lines.Additions, lines.Deletions, 1})
+		for _, file := range kept {
+			counts := lineCounts{file.Additions, file.Deletions}
+			if opts.codeOwners {
+				for _, owner := range files.owners.ownersOf(file.Filename) {
+					r.Owners = r.Owners.add(owner, counts)
+				}
+			}
+			if opts.languages {
+				r.Languages = r.Languages.add(languageOf(file.Filename), counts)
+			}
+		}
+	}
+	return rows.rows()
+}
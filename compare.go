@@ -0,0 +1,150 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// repoTotal stands in for the contributor of the rows comparing the totals
+// of a repository.
+const repoTotal = "(total)"
+
+// precedingPeriod returns the range of equal length that ends right before
+// start.
+func precedingPeriod(start, end time.Time) (time.Time, time.Time) {
+	baselineEnd := start.Add(-time.Second)
+	return baselineEnd.Add(-end.Sub(start)), baselineEnd
+}
+
+// comparisonSection compares every metric of the contributor rows, or of the
+// team rows with -teams-only, and of the repository totals with the
+// baseline period.
+func comparisonSection(opts options) section {
+	columns := contributorColumns(opts)
+	who := "Contributor"
+	key := func(r contributorRow) string { return r.Contributor }
+	if opts.teamsOnly {
+		columns = teamColumns(opts)
+		who = "Team"
+		key = func(r contributorRow) string { return r.Team }
+	}
+	metrics := metricColumns(columns)
+
+	return section{
+		name:   "comparison",
+		header: []string{"Repository", who, "Metric", "Baseline", "Current", "Change", "PercentChange", "BaselineStartDate", "BaselineEndDate", "StartDate", "EndDate"},
+		records: func(results []repoResult, start, end time.Time) [][]string {
+			var current, baseline []repoResult
+			for _, result := range results {
+				if result.Baseline != nil {
+					current = append(current, result)
+					baseline = append(baseline, *result.Baseline)
+				}
+			}
+			rowsOf := func(results []repoResult) []contributorRow {
+				if opts.teamsOnly {
+					return teamRows(results, opts.teams)
+				}
+				var rows []contributorRow
+				for _, result := range results {
+					rows = append(rows, result.Rows...)
+				}
+				return rows
+			}
+
+			var records [][]string
+			compare := func(repo, name string, before, after contributorRow) {
+				for _, m := range metrics {
+					b, a := m.value(before), m.value(after)
+					change, percent := formatChange(b, a)
+					records = append(records, []string{
+						repo, name, m.name, b, a, change, percent,
+						opts.baselineStart.Format("2006-01-02"),
+						opts.baselineEnd.Format("2006-01-02"),
+						start.Format("2006-01-02"),
+						end.Format("2006-01-02"),
+					})
+				}
+			}
+
+			// A contributor without activity in one of the periods is
+			// compared with an empty row, which still needs the repository
+			// total for its commit share.
+			repoCommits := func(results []repoResult) map[string]int {
+				commits := make(map[string]int)
+				for _, result := range results {
+					commits[result.Repository] = result.Commits
+					commits[allRepositories] += result.Commits
+				}
+				return commits
+			}
+			commitsBefore, commitsAfter := repoCommits(baseline), repoCommits(current)
+
+			type rowKey struct{ repo, name string }
+			before := make(map[rowKey]contributorRow)
+			var order []rowKey
+			for _, r := range rowsOf(baseline) {
+				k := rowKey{r.Repository, key(r)}
+				before[k] = r
+				order = append(order, k)
+			}
+			for _, r := range rowsOf(current) {
+				k := rowKey{r.Repository, key(r)}
+				b, ok := before[k]
+				if !ok {
+					b = contributorRow{RepoCommits: commitsBefore[k.repo]}
+				}
+				compare(k.repo, k.name, b, r)
+				delete(before, k)
+			}
+			for _, k := range order {
+				if r, ok := before[k]; ok {
+					compare(k.repo, k.name, r, contributorRow{RepoCommits: commitsAfter[k.repo]})
+				}
+			}
+
+			for i := range current {
+				compare(current[i].Repository, repoTotal, repoTotalRow(baseline[i]), repoTotalRow(current[i]))
+			}
+			return records
+		},
+	}
+}
+
+// repoTotalRow sums up the contributor rows of a repository.
+func repoTotalRow(result repoResult) contributorRow {
+	total := contributorRow{Repository: result.Repository, Contributor: repoTotal, RepoCommits: result.Commits}
+	for _, r := range result.Rows {
+		total.add(r)
+	}
+	return total
+}
+
+// formatChange returns the difference between two metric values, with the
+// precision of the more precise one, and the percentage change. Both are
+// empty when a value is missing, and the percentage also when the baseline
+// is zero.
+func formatChange(baseline, current string) (string, string) {
+	b, err := strconv.ParseFloat(baseline, 64)
+	if err != nil {
+		return "", ""
+	}
+	c, err := strconv.ParseFloat(current, 64)
+	if err != nil {
+		return "", ""
+	}
+	prec := max(decimals(baseline), decimals(current))
+	change := strconv.FormatFloat(c-b, 'f', prec, 64)
+	if b == 0 {
+		return change, ""
+	}
+	return change, strconv.FormatFloat((c-b)/b*100, 'f', 1, 64)
+}
+
+func decimals(value string) int {
+	if i := strings.IndexByte(value, '.'); i >= 0 {
+		return len(value) - i - 1
+	}
+	return 0
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	monthExpr    = regexp.MustCompile(`^(\d{4})-(\d{2})$`)
+	quarterExpr  = regexp.MustCompile(`^(\d{4})-[Qq]([1-4])$`)
+	weekExpr     = regexp.MustCompile(`^(\d{4})-[Ww](\d{2})$`)
+	relativeExpr = regexp.MustCompile(`^-(\d+)([dwmy])$`)
+)
+
+// dateExprHelp lists the forms of date expressions, for errors and prompts.
+const dateExprHelp = "YYYY-MM-DD, YYYY-MM, YYYY-Qn, YYYY-Wnn, -Nd, -Nw, -Nm, -Ny, today, yesterday, ytd, or this- or last- followed by week, month, quarter or year"
+
+// dateRange resolves a date expression to the first and the last day it
+// covers, at midnight in the location of now:
+//
+//   - 2024-03-15: that day.
+//   - 2024-03: that month.
+//   - 2024-Q3: that quarter.
+//   - 2024-W12: that ISO week, Monday to Sunday.
+//   - -30d, -4w, -6m, -1y: from that long ago until today.
+//   - today, yesterday, ytd: ytd is the start of the year until today.
+//   - this-week, this-month, this-quarter, this-year: until today.
+//   - last-week, last-month, last-quarter, last-year: the whole period.
+func dateRange(s string, now time.Time) (time.Time, time.Time, error) {
+	loc := now.Location()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	expr := s
+	s = strings.ToLower(strings.TrimSpace(s))
+	invalid := fmt.Errorf("invalid date %q: want %s", expr, dateExprHelp)
+
+	if day, err := time.ParseInLocation("2006-01-02", s, loc); err == nil {
+		return day, day, nil
+	}
+	if m := monthExpr.FindStringSubmatch(s); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		month, _ := strconv.Atoi(m[2])
+		if month < 1 || month > 12 {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid date %q: no month %d", expr, month)
+		}
+		start := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, loc)
+		return start, start.AddDate(0, 1, -1), nil
+	}
+	if m := quarterExpr.FindStringSubmatch(s); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		quarter, _ := strconv.Atoi(m[2])
+		start := time.Date(year, time.Month(3*quarter-2), 1, 0, 0, 0, 0, loc)
+		return start, start.AddDate(0, 3, -1), nil
+	}
+	if m := weekExpr.FindStringSubmatch(s); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		week, _ := strconv.Atoi(m[2])
+		start := isoWeekStart(year, week, loc)
+		if y, w := start.ISOWeek(); week < 1 || y != year || w != week {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid date %q: %d has no week %d", expr, year, week)
+		}
+		return start, start.AddDate(0, 0, 6), nil
+	}
+	if m := relativeExpr.FindStringSubmatch(s); m != nil {
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			return time.Time{}, time.Time{}, invalid
+		}
+		switch m[2] {
+		case "d":
+			return today.AddDate(0, 0, -n), today, nil
+		case "w":
+			return today.AddDate(0, 0, -7*n), today, nil
+		case "m":
+			return today.AddDate(0, -n, 0), today, nil
+		default:
+			return today.AddDate(-n, 0, 0), today, nil
+		}
+	}
+
+	switch s {
+	case "today":
+		return today, today, nil
+	case "yesterday":
+		yesterday := today.AddDate(0, 0, -1)
+		return yesterday, yesterday, nil
+	case "ytd":
+		s = "this-year"
+	}
+	which, unit, ok := strings.Cut(s, "-")
+	if !ok || (which != "this" && which != "last") {
+		return time.Time{}, time.Time{}, invalid
+	}
+	// start is the start of the current period, which is this long.
+	var start time.Time
+	var years, months, days int
+	switch unit {
+	case "week":
+		start, days = today.AddDate(0, 0, -(int(today.Weekday())+6)%7), 7
+	case "month":
+		start, months = time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, loc), 1
+	case "quarter":
+		start, months = time.Date(today.Year(), today.Month()-(today.Month()-1)%3, 1, 0, 0, 0, 0, loc), 3
+	case "year":
+		start, years = time.Date(today.Year(), 1, 1, 0, 0, 0, 0, loc), 1
+	default:
+		return time.Time{}, time.Time{}, invalid
+	}
+	if which == "this" {
+		return start, today, nil
+	}
+	return start.AddDate(-years, -months, -days), start.AddDate(0, 0, -1), nil
+}
+
+// isoWeekStart returns the Monday of an ISO week: week 1 is the week with
+// the year's first Thursday, so it contains 4 January.
+func isoWeekStart(year, week int, loc *time.Location) time.Time {
+	jan4 := time.Date(year, 1, 4, 0, 0, 0, 0, loc)
+	monday := jan4.AddDate(0, 0, -(int(jan4.Weekday())+6)%7)
+	return monday.AddDate(0, 0, 7*(week-1))
+}
+
+// endOfDay returns the last second of the day of t.
+func endOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 23, 59, 59, 0, t.Location())
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// testNow is a Wednesday.
+var testNow = time.Date(2024, 5, 15, 10, 30, 0, 0, time.UTC)
+
+func day(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestDateRange(t *testing.T) {
+	tests := []struct {
+		expr        string
+		first, last string
+	}{
+		{"2024-03-15", "2024-03-15", "2024-03-15"},
+		{" 2024-03-15 ", "2024-03-15", "2024-03-15"},
+		{"2024-03", "2024-03-01", "2024-03-31"},
+		{"2024-02", "2024-02-01", "2024-02-29"},
+		{"2023-02", "2023-02-01", "2023-02-28"},
+		{"2024-12", "2024-12-01", "2024-12-31"},
+		{"2024-Q1", "2024-01-01", "2024-03-31"},
+		{"2024-q2", "2024-04-01", "2024-06-30"},
+		{"2024-Q3", "2024-07-01", "2024-09-30"},
+		{"2024-Q4", "2024-10-01", "2024-12-31"},
+		{"2024-W01", "2024-01-01", "2024-01-07"},
+		{"2024-w12", "2024-03-18", "2024-03-24"},
+		{"2020-W53", "2020-12-28", "2021-01-03"},
+		{"2021-W01", "2021-01-04", "2021-01-10"},
+		{"-30d", "2024-04-15", "2024-05-15"},
+		{"-2w", "2024-05-01", "2024-05-15"},
+		{"-6m", "2023-11-15", "2024-05-15"},
+		{"-1y", "2023-05-15", "2024-05-15"},
+		{"today", "2024-05-15", "2024-05-15"},
+		{"Yesterday", "2024-05-14", "2024-05-14"},
+		{"ytd", "2024-01-01", "2024-05-15"},
+		{"this-week", "2024-05-13", "2024-05-15"},
+		{"this-month", "2024-05-01", "2024-05-15"},
+		{"this-quarter", "2024-04-01", "2024-05-15"},
+		{"this-year", "2024-01-01", "2024-05-15"},
+		{"last-week", "2024-05-06", "2024-05-12"},
+		{"last-month", "2024-04-01", "2024-04-30"},
+		{"last-quarter", "2024-01-01", "2024-03-31"},
+		{"last-year", "2023-01-01", "2023-12-31"},
+	}
+	for _, tt := range tests {
+		first, last, err := dateRange(tt.expr, testNow)
+		if err != nil {
+			t.Errorf("dateRange(%q): %v", tt.expr, err)
+			continue
+		}
+		if !first.Equal(day(tt.first)) || !last.Equal(day(tt.last)) {
+			t.Errorf("dateRange(%q) = %s to %s, want %s to %s", tt.expr,
+				first.Format("2006-01-02"), last.Format("2006-01-02"), tt.first, tt.last)
+		}
+	}
+}
+
+func TestDateRangePeriodBoundaries(t *testing.T) {
+	// On a Sunday, the week started six days before.
+	sunday := time.Date(2024, 5, 19, 0, 0, 0, 0, time.UTC)
+	first, last, err := dateRange("this-week", sunday)
+	if err != nil || !first.Equal(day("2024-05-13")) || !last.Equal(day("2024-05-19")) {
+		t.Errorf("this-week on a Sunday = %s to %s, %v", first, last, err)
+	}
+	// In January, last month and last quarter are in the year before.
+	january := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	first, last, err = dateRange("last-month", january)
+	if err != nil || !first.Equal(day("2023-12-01")) || !last.Equal(day("2023-12-31")) {
+		t.Errorf("last-month in January = %s to %s, %v", first, last, err)
+	}
+	first, last, err = dateRange("last-quarter", january)
+	if err != nil || !first.Equal(day("2023-10-01")) || !last.Equal(day("2023-12-31")) {
+		t.Errorf("last-quarter in January = %s to %s, %v", first, last, err)
+	}
+}
+
+func TestDateRangeErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"foo",
+		"2024-13",
+		"2024-00",
+		"2024-Q5",
+		"2024-W00",
+		"2021-W53",
+		"2024-W54",
+		"2024-02-30",
+		"-30",
+		"-3x",
+		"+3d",
+		"next-month",
+		"this-decade",
+		"this",
+	} {
+		if _, _, err := dateRange(expr, testNow); err == nil {
+			t.Errorf("dateRange(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestParseDates(t *testing.T) {
+	tests := []struct {
+		start, end string
+		want       [2]time.Time
+	}{
+		// Without dates, the month before today.
+		{"", "", [2]time.Time{
+			time.Date(2024, 4, 15, 23, 59, 59, 0, time.UTC),
+			time.Date(2024, 5, 15, 23, 59, 59, 0, time.UTC),
+		}},
+		// A start day alone runs until today.
+		{"2024-01-01", "", [2]time.Time{day("2024-01-01"), time.Date(2024, 5, 15, 23, 59, 59, 0, time.UTC)}},
+		// A longer start period alone is the whole period.
+		{"2024-Q1", "", [2]time.Time{day("2024-01-01"), time.Date(2024, 3, 31, 23, 59, 59, 0, time.UTC)}},
+		{"last-month", "", [2]time.Time{day("2024-04-01"), time.Date(2024, 4, 30, 23, 59, 59, 0, time.UTC)}},
+		// The end is the last day of the end period.
+		{"2024-01", "2024-02", [2]time.Time{day("2024-01-01"), time.Date(2024, 2, 29, 23, 59, 59, 0, time.UTC)}},
+		{"", "2024-03-15", [2]time.Time{
+			time.Date(2024, 2, 15, 23, 59, 59, 0, time.UTC),
+			time.Date(2024, 3, 15, 23, 59, 59, 0, time.UTC),
+		}},
+	}
+	for _, tt := range tests {
+		start, end, err := parseDates(tt.start, tt.end, testNow)
+		if err != nil {
+			t.Errorf("parseDates(%q, %q): %v", tt.start, tt.end, err)
+			continue
+		}
+		if !start.Equal(tt.want[0]) || !end.Equal(tt.want[1]) {
+			t.Errorf("parseDates(%q, %q) = %s to %s, want %s to %s", tt.start, tt.end, start, end, tt.want[0], tt.want[1])
+		}
+	}
+}
+
+func TestParseDatesErrors(t *testing.T) {
+	for _, tt := range []struct{ start, end string }{
+		{"foo", ""},
+		{"", "foo"},
+		{"2024-03", "2024-02"},
+		{"2024-05-16", "today"},
+	} {
+		if _, _, err := parseDates(tt.start, tt.end, testNow); err == nil {
+			t.Errorf("parseDates(%q, %q) succeeded, want an error", tt.start, tt.end)
+		}
+	}
+}
+
+func TestParseDatesLocation(t *testing.T) {
+	loc := time.FixedZone("UTC+9", 9*60*60)
+	// Still 15 May in UTC, but already 16 May in loc.
+	now := time.Date(2024, 5, 15, 20, 0, 0, 0, time.UTC).In(loc)
+	start, end, err := parseDates("today", "", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, 5, 16, 0, 0, 0, 0, loc); !start.Equal(want) || start.Location() != loc {
+		t.Errorf("start = %s, want %s", start, want)
+	}
+	if want := time.Date(2024, 5, 16, 23, 59, 59, 0, loc); !end.Equal(want) || end.Location() != loc {
+		t.Errorf("end = %s, want %s", end, want)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"slices"
+	"strconv"
+	"time"
+)
+
+const secondsPerWeek = 7 * 24 * 60 * 60
+
+// weeklyActivity maps the Unix time of the Sunday, 00:00 UTC, starting a
+// week to the activity within it, like GitHub's contributor statistics.
+type weeklyActivity map[int64]weekCounts
+
+type weekCounts struct {
+	Additions int
+	Deletions int
+	Commits   int
+}
+
+// weekOf returns the start of the week t falls in.
+func weekOf(t time.Time) int64 {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day()-int(t.Weekday()), 0, 0, 0, 0, time.UTC).Unix()
+}
+
+// add returns w with c added to week, allocating w if needed.
+func (w weeklyActivity) add(week int64, c weekCounts) weeklyActivity {
+	if w == nil {
+		w = make(weeklyActivity)
+	}
+	total := w[week]
+	total.Additions += c.Additions
+	total.Deletions += c.Deletions
+	total.Commits += c.Commits
+	w[week] = total
+	return w
+}
+
+func (w weeklyActivity) merge(other weeklyActivity) weeklyActivity {
+	for week, c := range other {
+		w = w.add(week, c)
+	}
+	return w
+}
+
+// activeWeeks returns the weeks with commits, in order.
+func (w weeklyActivity) activeWeeks() []int64 {
+	var weeks []int64
+	for week, c := range w {
+		if c.Commits > 0 {
+			weeks = append(weeks, week)
+		}
+	}
+	slices.Sort(weeks)
+	return weeks
+}
+
+// longestStreak returns the largest number of consecutive weeks with
+// commits.
+func (w weeklyActivity) longestStreak() int {
+	longest, current := 0, 0
+	var previous int64
+	for _, week := range w.activeWeeks() {
+		if current > 0 && week-previous == secondsPerWeek {
+			current++
+		} else {
+			current = 1
+		}
+		longest = max(longest, current)
+		previous = week
+	}
+	return longest
+}
+
+var derivedColumns = []column{
+	{"Net", func(r contributorRow) string { return strconv.Itoa(r.Additions - r.Deletions) }},
+	{"Churn", func(r contributorRow) string { return strconv.Itoa(r.Additions + r.Deletions) }},
+	{"LinesPerCommit", func(r contributorRow) string {
+		if r.Commits == 0 {
+			return ""
+		}
+		return strconv.FormatFloat(float64(r.Additions+r.Deletions)/float64(r.Commits), 'f', 1, 64)
+	}},
+	{"ActiveWeeks", func(r contributorRow) string { return strconv.Itoa(len(r.Weeks.activeWeeks())) }},
+	{"LongestStreakWeeks", func(r contributorRow) string { return strconv.Itoa(r.Weeks.longestStreak()) }},
+	{"CommitShare", func(r contributorRow) string {
+		if r.RepoCommits == 0 {
+			return ""
+		}
+		return strconv.FormatFloat(float64(r.Commits)/float64(r.RepoCommits), 'f', 3, 64)
+	}},
+}
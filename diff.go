@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// table is a report read back from a file: a header and its records.
+type table struct {
+	header  []string
+	records [][]string
+}
+
+// keyColumns identify a row of a report, so that the rows of two reports
+// can be matched. The other columns hold metrics, except those in
+// ignoredColumns. Team only identifies the rows of team reports: in
+// contributor reports it is an attribute of the contributor, who may change
+// teams between two reports.
+var (
+	keyColumns     = []string{"Repository", "Contributor", "Team", "Owner", "Language", "Metric", "Week", "Date", "Day", "Hour"}
+	ignoredColumns = []string{"Name", "Logins", "Members", "StartDate", "EndDate", "TimeZone", "BaselineStartDate", "BaselineEndDate"}
+)
+
+// readTable reads a report written by ghstats: a CSV file, or JSON output
+// or a snapshot from the archive, whose contributor rows are read with
+// every column.
+func readTable(path string) (table, error) {
+	if filepath.Ext(path) == ".json" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return table{}, err
+		}
+		// Snapshots hold the results of every repository, JSON output
+		// also the contributor rows of all of them.
+		var model struct {
+			Results      []repoResult
+			Contributors []contributorRow
+		}
+		if err := json.Unmarshal(data, &model); err != nil {
+			return table{}, fmt.Errorf("%s: %w", path, err)
+		}
+		rows := model.Contributors
+		for _, result := range model.Results {
+			rows = append(rows, result.Rows...)
+		}
+		columns := slices.Concat(baseColumns, pullColumns, reviewColumns, excludedColumns, derivedColumns, issueColumns)
+		header, records := renderRows(columns, rows)
+		return table{header, records}, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return table{}, err
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return table{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(records) == 0 {
+		return table{}, fmt.Errorf("%s: empty file", path)
+	}
+	return table{records[0], records[1:]}, nil
+}
+
+// keyed indexes the records of t by their key columns, keeping the order in
+// which the keys first appear.
+func (t table) keyed(keys []int) (map[string][]string, []string) {
+	byKey := make(map[string][]string)
+	var order []string
+	for _, record := range t.records {
+		var parts []string
+		for _, i := range keys {
+			parts = append(parts, record[i])
+		}
+		k := strings.Join(parts, "\x00")
+		if _, ok := byKey[k]; !ok {
+			order = append(order, k)
+		}
+		byKey[k] = record
+	}
+	return byKey, order
+}
+
+// runDiff implements the diff subcommand, which matches the rows of two
+// reports and lists the rows only in one of them and the metrics that
+// changed:
+//
+//	ghstats diff [-format table|csv|md] a.csv b.csv
+func runDiff(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	format := flags.String("format", formatTable, "output `format`: table, csv or md")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return fmt.Errorf("usage: ghstats diff [-format table|csv|md] a.csv b.csv")
+	}
+	a, err := readTable(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	b, err := readTable(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	// Rows are matched by the key columns of both files, and metrics are
+	// compared where both files have them.
+	var keyNames []string
+	var keysA, keysB []int
+	teamKey := !slices.Contains(a.header, "Contributor") && !slices.Contains(b.header, "Contributor")
+	for _, name := range keyColumns {
+		if name == "Team" && !teamKey {
+			continue
+		}
+		i, j := slices.Index(a.header, name), slices.Index(b.header, name)
+		if i >= 0 && j >= 0 {
+			keyNames = append(keyNames, name)
+			keysA, keysB = append(keysA, i), append(keysB, j)
+		}
+	}
+	if len(keyNames) == 0 {
+		return fmt.Errorf("the files have no key column in common, e.g. Repository")
+	}
+	type metric struct {
+		name string
+		a, b int
+	}
+	var metrics []metric
+	for i, name := range a.header {
+		// A contributor's team is compared like a metric.
+		attribute := name == "Team" && !teamKey
+		if !attribute && (slices.Contains(keyColumns, name) || slices.Contains(ignoredColumns, name)) {
+			continue
+		}
+		if j := slices.Index(b.header, name); j >= 0 {
+			metrics = append(metrics, metric{name, i, j})
+		}
+	}
+
+	rowsA, orderA := a.keyed(keysA)
+	rowsB, orderB := b.keyed(keysB)
+	keyOf := func(record []string, keys []int) []string {
+		var values []string
+		for _, i := range keys {
+			values = append(values, record[i])
+		}
+		return values
+	}
+
+	header := slices.Concat(keyNames, []string{"Change", "Metric", "Before", "After", "Delta", "PercentChange"})
+	var records [][]string
+	var added, removed, changed int
+	for _, k := range orderA {
+		recordA := rowsA[k]
+		recordB, ok := rowsB[k]
+		if !ok {
+			removed++
+			records = append(records, slices.Concat(keyOf(recordA, keysA), []string{"removed", "", "", "", "", ""}))
+			continue
+		}
+		rowChanged := false
+		for _, m := range metrics {
+			before, after := recordA[m.a], recordB[m.b]
+			if before == after {
+				continue
+			}
+			rowChanged = true
+			delta, percent := formatChange(before, after)
+			records = append(records, slices.Concat(keyOf(recordA, keysA), []string{"changed", m.name, before, after, delta, percent}))
+		}
+		if rowChanged {
+			changed++
+		}
+	}
+	for _, k := range orderB {
+		if _, ok := rowsA[k]; !ok {
+			added++
+			records = append(records, slices.Concat(keyOf(rowsB[k], keysB), []string{"added", "", "", "", "", ""}))
+		}
+	}
+
+	if err := writeFormatted(os.Stdout, *format, header, records); err != nil {
+		return err
+	}
+	if *format == formatTable {
+		fmt.Printf("\n%d added, %d removed, %d changed, %d unchanged\n", added, removed, changed, len(orderA)-removed-changed)
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+type Deployment struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type DeploymentStatus struct {
+	State     string    `json:"state"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Release struct {
+	Draft       bool       `json:"draft"`
+	Prerelease  bool       `json:"prerelease"`
+	CreatedAt   time.Time  `json:"created_at"`
+	PublishedAt *time.Time `json:"published_at"`
+}
+
+type PullRequestCommit struct {
+	Commit struct {
+		Author struct {
+			Date time.Time `json:"date"`
+		} `json:"author"`
+	} `json:"commit"`
+}
+
+// doraMetrics holds the DORA metrics of a repository. Failures are the
+// reverts and hotfixes merged within the range.
+type doraMetrics struct {
+	Source       string
+	Deployments  int
+	LeadTimes    []time.Duration
+	Failures     int
+	RestoreTimes []time.Duration
+}
+
+// fetchDeployTimes returns, in ascending order, when the repository was
+// deployed on or after since. Deployments to the environment, or to any
+// environment if it is empty, count from their first successful status, even
+// if a later deployment has since made them inactive. Published releases
+// stand in for deployments when the repository has none. The second return
+// value names the source used, and is empty when there was neither.
+func fetchDeployTimes(client *http.Client, owner, repo, token, environment string, since time.Time) ([]time.Time, string, error) {
+	var times []time.Time
+	url := fmt.Sprintf("%s/repos/%s/%s/deployments?per_page=100", githubAPI, owner, repo)
+	if environment != "" {
+		url += "&environment=" + neturl.QueryEscape(environment)
+	}
+	for url != "" {
+		var page []Deployment
+		next, err := getJSON(client, url, token, &page)
+		if err != nil {
+			return nil, "", err
+		}
+		for _, deployment := range page {
+			if deployment.CreatedAt.Before(since) {
+				next = ""
+				break
+			}
+			deployed, ok, err := fetchDeployedAt(client, owner, repo, token, deployment.ID)
+			if err != nil {
+				return nil, "", err
+			}
+			if ok {
+				times = append(times, deployed)
+			}
+		}
+		url = next
+	}
+	if len(times) > 0 {
+		slices.SortFunc(times, time.Time.Compare)
+		return times, "deployments", nil
+	}
+
+	url = fmt.Sprintf("%s/repos/%s/%s/releases?per_page=100", githubAPI, owner, repo)
+	for url != "" {
+		var page []Release
+		next, err := getJSON(client, url, token, &page)
+		if err != nil {
+			return nil, "", err
+		}
+		for _, release := range page {
+			if release.CreatedAt.Before(since) {
+				next = ""
+				break
+			}
+			if !release.Draft && !release.Prerelease && release.PublishedAt != nil {
+				times = append(times, *release.PublishedAt)
+			}
+		}
+		url = next
+	}
+	if len(times) > 0 {
+		slices.SortFunc(times, time.Time.Compare)
+		return times, "releases", nil
+	}
+	return nil, "", nil
+}
+
+// fetchDeployedAt returns when a deployment first succeeded. Its statuses
+// are listed newest first, and later ones such as inactive, which GitHub sets
+// when a newer deployment to the environment succeeds, are skipped.
+func fetchDeployedAt(client *http.Client, owner, repo, token string, id int64) (time.Time, bool, error) {
+	var deployed time.Time
+	url := fmt.Sprintf("%s/repos/%s/%s/deployments/%d/statuses?per_page=100", githubAPI, owner, repo, id)
+	for url != "" {
+		var page []DeploymentStatus
+		next, err := getJSON(client, url, token, &page)
+		if err != nil {
+			return time.Time{}, false, err
+		}
+		for _, status := range page {
+			if status.State == "success" {
+				deployed = status.CreatedAt
+			}
+		}
+		url = next
+	}
+	return deployed, !deployed.IsZero(), nil
+}
+
+// fetchFirstCommitTimes returns when the first commit of each pull request
+// merged within the range was authored, keyed by number.
+func fetchFirstCommitTimes(client *http.Client, owner, repo, token string, prs []PullRequest, start, end time.Time) (map[int]time.Time, error) {
+	times := make(map[int]time.Time)
+	for _, pr := range prs {
+		if pr.MergedAt == nil || !inRange(*pr.MergedAt, start, end) {
+			continue
+		}
+		var commits []PullRequestCommit
+		url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d/commits?per_page=1", githubAPI, owner, repo, pr.Number)
+		if _, err := getJSON(client, url, token, &commits); err != nil {
+			return nil, err
+		}
+		if len(commits) > 0 {
+			times[pr.Number] = commits[0].Commit.Author.Date
+		}
+	}
+	return times, nil
+}
+
+// isFailureFix reports whether a pull request reverts or hotfixes an earlier
+// change, which makes that change count as failed.
+func isFailureFix(pr PullRequest) bool {
+	if strings.HasPrefix(pr.Title, "Revert ") {
+		return true
+	}
+	for _, label := range pr.Labels {
+		if strings.Contains(strings.ToLower(label.Name), "hotfix") {
+			return true
+		}
+	}
+	return false
+}
+
+// processDORA computes the DORA metrics of a repository. A change's lead
+// time runs from its first commit to the first deployment at or after its
+// merge. Time to restore runs from opening a revert or hotfix to the
+// deployment that shipped it.
+func processDORA(prs []PullRequest, firstCommits map[int]time.Time, deploys []time.Time, source string, start, end time.Time) doraMetrics {
+	metrics := doraMetrics{Source: source}
+	for _, deploy := range deploys {
+		if inRange(deploy, start, end) {
+			metrics.Deployments++
+		}
+	}
+	deployedAt := func(merged time.Time) (time.Time, bool) {
+		i, _ := slices.BinarySearchFunc(deploys, merged, time.Time.Compare)
+		if i == len(deploys) {
+			return time.Time{}, false
+		}
+		return deploys[i], true
+	}
+
+	for _, pr := range prs {
+		if pr.MergedAt == nil || !inRange(*pr.MergedAt, start, end) {
+			continue
+		}
+		failure := isFailureFix(pr)
+		if failure {
+			metrics.Failures++
+		}
+		deploy, ok := deployedAt(*pr.MergedAt)
+		if !ok {
+			continue
+		}
+		if first, ok := firstCommits[pr.Number]; ok {
+			metrics.LeadTimes = append(metrics.LeadTimes, deploy.Sub(first))
+		}
+		if failure {
+			metrics.RestoreTimes = append(metrics.RestoreTimes, deploy.Sub(pr.CreatedAt))
+		}
+	}
+	return metrics
+}
@@ -0,0 +1,521 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// expr is a compiled -where or -column expression. It is evaluated against
+// a row of the report, and yields a float64, a string, a bool, or nil for
+// an empty cell.
+type expr struct {
+	source string
+	eval   func(row func(name string) string) (any, error)
+	// condition is whether the expression yields a bool.
+	condition bool
+	// idents are the columns the expression refers to, normalized.
+	idents []string
+}
+
+// columnAliases are short names for columns, normalized.
+var columnAliases = map[string]string{
+	"repo":  "repository",
+	"login": "contributor",
+}
+
+// normalizeColumn returns the name columns are looked up by: lowercased,
+// without underscores, so that prs_merged refers to PRsMerged.
+func normalizeColumn(name string) string {
+	name = strings.ToLower(strings.ReplaceAll(name, "_", ""))
+	if alias, ok := columnAliases[name]; ok {
+		return alias
+	}
+	return name
+}
+
+// parseExpr compiles an expression. It supports numbers, "strings" and
+// column names, combined with + - * / %, the comparisons == != < <= > >=,
+// =~ and !~ to match a regular expression, ! && || and parentheses.
+// Operands of the wrong kind, such as text columns in arithmetic or numbers
+// joined by &&, are rejected here rather than when a row is evaluated.
+func parseExpr(source string) (expr, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return expr{}, err
+	}
+	p := &exprParser{tokens: tokens}
+	n, err := p.or()
+	if err != nil {
+		return expr{}, err
+	}
+	if p.pos < len(p.tokens) {
+		return expr{}, fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+	return expr{source: source, eval: n.eval, condition: n.kind == kindBool, idents: p.idents}, nil
+}
+
+type tokenKind int
+
+const (
+	tokenNumber tokenKind = iota
+	tokenString
+	tokenIdent
+	tokenOperator
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "!~", "<", ">", "!", "+", "-", "*", "/", "%", "(", ")"}
+
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(source); {
+		c := rune(source[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(source) && source[end] != '"' {
+				if source[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(source) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			s, err := strconv.Unquote(source[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string %s", source[i:end+1])
+			}
+			tokens = append(tokens, token{tokenString, s})
+			i = end + 1
+		case unicode.IsDigit(c) || c == '.':
+			end := i
+			for end < len(source) && (unicode.IsDigit(rune(source[end])) || source[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, token{tokenNumber, source[i:end]})
+			i = end
+		case unicode.IsLetter(c) || c == '_':
+			end := i
+			for end < len(source) && (unicode.IsLetter(rune(source[end])) || unicode.IsDigit(rune(source[end])) || source[end] == '_') {
+				end++
+			}
+			tokens = append(tokens, token{tokenIdent, source[i:end]})
+			i = end
+		default:
+			found := false
+			for _, op := range operators {
+				if strings.HasPrefix(source[i:], op) {
+					tokens = append(tokens, token{tokenOperator, op})
+					i += len(op)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("unexpected %q", c)
+			}
+		}
+	}
+	return tokens, nil
+}
+
+type evalFunc = func(row func(name string) string) (any, error)
+
+// valueKind is what an expression yields, as far as is known before it is
+// evaluated against a row.
+type valueKind int
+
+const (
+	// kindCell is a cell of a column, which may hold a number, text or
+	// nothing.
+	kindCell valueKind = iota
+	kindNumber
+	kindString
+	kindBool
+)
+
+// stringColumns are the columns of the report that always hold text,
+// normalized.
+var stringColumns = []string{"repository", "contributor", "name", "team", "logins", "members", "timezone"}
+
+// node is a parsed part of an expression.
+type node struct {
+	eval evalFunc
+	kind valueKind
+	// desc describes the node in errors, e.g. "the text column Team".
+	desc string
+}
+
+func (n node) need(kind valueKind, op string) error {
+	if kind == kindNumber && (n.kind == kindNumber || n.kind == kindCell) || n.kind == kind {
+		return nil
+	}
+	what := "a condition"
+	if kind == kindNumber {
+		what = "a number"
+	}
+	return fmt.Errorf("%s needs %s, not %s", op, what, n.desc)
+}
+
+type exprParser struct {
+	tokens []token
+	pos    int
+	idents []string
+}
+
+// accept consumes the next token if it is one of the operators.
+func (p *exprParser) accept(ops ...string) (string, bool) {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].kind == tokenOperator {
+		for _, op := range ops {
+			if p.tokens[p.pos].text == op {
+				p.pos++
+				return op, true
+			}
+		}
+	}
+	return "", false
+}
+
+func (p *exprParser) or() (node, error) {
+	return p.logical("||", p.and)
+}
+
+func (p *exprParser) and() (node, error) {
+	return p.logical("&&", p.not)
+}
+
+// logical parses conditions joined by op, which short-circuits.
+func (p *exprParser) logical(op string, operand func() (node, error)) (node, error) {
+	left, err := operand()
+	if err != nil {
+		return node{}, err
+	}
+	for {
+		if _, ok := p.accept(op); !ok {
+			return left, nil
+		}
+		right, err := operand()
+		if err != nil {
+			return node{}, err
+		}
+		if err := left.need(kindBool, op); err != nil {
+			return node{}, err
+		}
+		if err := right.need(kindBool, op); err != nil {
+			return node{}, err
+		}
+		l := left.eval
+		left = node{func(row func(string) string) (any, error) {
+			a, err := evalBool(l, row, op)
+			if err != nil || a == (op == "||") {
+				return a, err
+			}
+			return evalBool(right.eval, row, op)
+		}, kindBool, "a condition"}
+	}
+}
+
+func (p *exprParser) not() (node, error) {
+	if _, ok := p.accept("!"); ok {
+		operand, err := p.not()
+		if err != nil {
+			return node{}, err
+		}
+		if err := operand.need(kindBool, "!"); err != nil {
+			return node{}, err
+		}
+		return node{func(row func(string) string) (any, error) {
+			b, err := evalBool(operand.eval, row, "!")
+			return !b, err
+		}, kindBool, "a condition"}, nil
+	}
+	return p.comparison()
+}
+
+func (p *exprParser) comparison() (node, error) {
+	leftNode, err := p.sum()
+	if err != nil {
+		return node{}, err
+	}
+	op, ok := p.accept("==", "!=", "<=", ">=", "<", ">", "=~", "!~")
+	if !ok {
+		return leftNode, nil
+	}
+	rightNode, err := p.sum()
+	if err != nil {
+		return node{}, err
+	}
+	left, right := leftNode.eval, rightNode.eval
+
+	if op == "=~" || op == "!~" {
+		// Patterns are mostly literals, which are compiled once.
+		var literal *regexp.Regexp
+		if pattern, err := right(func(string) string { return "" }); err == nil {
+			if s, ok := pattern.(string); ok {
+				if literal, err = regexp.Compile(s); err != nil {
+					return node{}, err
+				}
+			}
+		}
+		return node{func(row func(string) string) (any, error) {
+			a, err := left(row)
+			if err != nil {
+				return nil, err
+			}
+			re := literal
+			if re == nil {
+				b, err := right(row)
+				if err != nil {
+					return nil, err
+				}
+				if re, err = regexp.Compile(formatValue(b)); err != nil {
+					return nil, err
+				}
+			}
+			return re.MatchString(formatValue(a)) == (op == "=~"), nil
+		}, kindBool, "a condition"}, nil
+	}
+
+	return node{func(row func(string) string) (any, error) {
+		a, err := left(row)
+		if err != nil {
+			return nil, err
+		}
+		b, err := right(row)
+		if err != nil {
+			return nil, err
+		}
+		if a == nil || b == nil {
+			// An empty cell only equals another empty cell or "".
+			switch op {
+			case "==":
+				return formatValue(a) == formatValue(b), nil
+			case "!=":
+				return formatValue(a) != formatValue(b), nil
+			}
+			return false, nil
+		}
+		var c int
+		x, xNum := a.(float64)
+		y, yNum := b.(float64)
+		if xNum && yNum {
+			c = compareFloats(x, y)
+		} else {
+			c = strings.Compare(formatValue(a), formatValue(b))
+		}
+		switch op {
+		case "==":
+			return c == 0, nil
+		case "!=":
+			return c != 0, nil
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		default:
+			return c >= 0, nil
+		}
+	}, kindBool, "a condition"}, nil
+}
+
+func (p *exprParser) sum() (node, error) {
+	return p.arithmetic(p.product, "+", "-")
+}
+
+func (p *exprParser) product() (node, error) {
+	return p.arithmetic(p.unary, "*", "/", "%")
+}
+
+// arithmetic parses operands joined by the operators. An empty cell makes
+// the result empty.
+func (p *exprParser) arithmetic(operand func() (node, error), ops ...string) (node, error) {
+	left, err := operand()
+	if err != nil {
+		return node{}, err
+	}
+	for {
+		op, ok := p.accept(ops...)
+		if !ok {
+			return left, nil
+		}
+		right, err := operand()
+		if err != nil {
+			return node{}, err
+		}
+		if err := left.need(kindNumber, op); err != nil {
+			return node{}, err
+		}
+		if err := right.need(kindNumber, op); err != nil {
+			return node{}, err
+		}
+		l := left.eval
+		left = node{func(row func(string) string) (any, error) {
+			a, err := evalNumber(l, row, op)
+			if err != nil || a == nil {
+				return nil, err
+			}
+			b, err := evalNumber(right.eval, row, op)
+			if err != nil || b == nil {
+				return nil, err
+			}
+			x, y := a.(float64), b.(float64)
+			switch op {
+			case "+":
+				return x + y, nil
+			case "-":
+				return x - y, nil
+			case "*":
+				return x * y, nil
+			case "/":
+				if y == 0 {
+					return nil, nil
+				}
+				return x / y, nil
+			default:
+				if y == 0 {
+					return nil, nil
+				}
+				return math.Mod(x, y), nil
+			}
+		}, kindNumber, "a number"}
+	}
+}
+
+func (p *exprParser) unary() (node, error) {
+	if _, ok := p.accept("-"); ok {
+		operand, err := p.unary()
+		if err != nil {
+			return node{}, err
+		}
+		if err := operand.need(kindNumber, "-"); err != nil {
+			return node{}, err
+		}
+		return node{func(row func(string) string) (any, error) {
+			v, err := evalNumber(operand.eval, row, "-")
+			if err != nil || v == nil {
+				return nil, err
+			}
+			return -v.(float64), nil
+		}, kindNumber, "a number"}, nil
+	}
+	return p.primary()
+}
+
+func (p *exprParser) primary() (node, error) {
+	if _, ok := p.accept("("); ok {
+		inner, err := p.or()
+		if err != nil {
+			return node{}, err
+		}
+		if _, ok := p.accept(")"); !ok {
+			return node{}, fmt.Errorf("missing )")
+		}
+		return inner, nil
+	}
+	if p.pos >= len(p.tokens) {
+		return node{}, fmt.Errorf("unexpected end of expression")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	switch t.kind {
+	case tokenNumber:
+		n, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return node{}, fmt.Errorf("invalid number %q", t.text)
+		}
+		return node{func(func(string) string) (any, error) { return n, nil }, kindNumber, t.text}, nil
+	case tokenString:
+		return node{func(func(string) string) (any, error) { return t.text, nil }, kindString, strconv.Quote(t.text)}, nil
+	case tokenIdent:
+		switch t.text {
+		case "true", "false":
+			b := t.text == "true"
+			return node{func(func(string) string) (any, error) { return b, nil }, kindBool, t.text}, nil
+		}
+		name := normalizeColumn(t.text)
+		p.idents = append(p.idents, name)
+		n := node{func(row func(string) string) (any, error) {
+			return cellValue(row(name)), nil
+		}, kindCell, "the column " + t.text}
+		if slices.Contains(stringColumns, name) {
+			n.kind, n.desc = kindString, "the text column "+t.text
+		}
+		return n, nil
+	}
+	return node{}, fmt.Errorf("unexpected %q", t.text)
+}
+
+// cellValue converts a cell of the report into a value: a number if it
+// looks like one, nil if it is empty, and a string otherwise.
+func cellValue(cell string) any {
+	if cell == "" {
+		return nil
+	}
+	if n, err := strconv.ParseFloat(cell, 64); err == nil {
+		return n
+	}
+	return cell
+}
+
+func evalBool(f evalFunc, row func(string) string, op string) (bool, error) {
+	v, err := f(row)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("%s needs a condition, not %q", op, formatValue(v))
+	}
+	return b, nil
+}
+
+// evalNumber evaluates f, which must yield a number or an empty cell.
+func evalNumber(f evalFunc, row func(string) string, op string) (any, error) {
+	v, err := f(row)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	if _, ok := v.(float64); !ok {
+		return nil, fmt.Errorf("%s needs a number, not %q", op, formatValue(v))
+	}
+	return v, nil
+}
+
+func compareFloats(x, y float64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+// formatValue renders a value as a cell. Numbers are rounded to two
+// decimals.
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// testRow is a row of the report as expressions see it.
+func testRow(cells map[string]string) func(string) string {
+	return func(name string) string { return cells[name] }
+}
+
+func TestParseExpr(t *testing.T) {
+	row := testRow(map[string]string{
+		"repository":    "owner/repo",
+		"contributor":   "octocat",
+		"additions":     "150",
+		"deletions":     "50",
+		"commits":       "10",
+		"prsmerged":     "0",
+		"mediantomerge": "",
+		"lines":         "2.5",
+	})
+	tests := []struct {
+		source string
+		want   any
+	}{
+		// Numbers and arithmetic, with the usual precedence.
+		{"1 + 2 * 3", 7.0},
+		{"(1 + 2) * 3", 9.0},
+		{"10 - 4 - 3", 3.0},
+		{"7 % 4", 3.0},
+		{"-3 + 5", 2.0},
+		{"- -2", 2.0},
+		{"1.5 * 2", 3.0},
+		{"additions - deletions", 100.0},
+		{"additions / commits", 15.0},
+		{"lines * 2", 5.0},
+
+		// Division by zero and empty cells make the result empty.
+		{"additions / prs_merged", nil},
+		{"additions % 0", nil},
+		{"median_to_merge + 1", nil},
+		{"-median_to_merge", nil},
+
+		// Column names ignore case and underscores, and have aliases.
+		{"Additions", 150.0},
+		{"ADD_ITIONS", 150.0},
+		{"repo", "owner/repo"},
+		{"login", "octocat"},
+		{"unknown", nil},
+
+		// Strings and comparisons.
+		{`"a\"b"`, `a"b`},
+		{"commits == 10", true},
+		{"commits != 10", false},
+		{"commits < 9", false},
+		{"commits <= 10", true},
+		{"commits > 9.5", true},
+		{"commits >= 11", false},
+		{`contributor == "octocat"`, true},
+		{`contributor < "p"`, true},
+		{`repo =~ "^owner/"`, true},
+		{`repo !~ "^owner/"`, false},
+		{`repo =~ contributor`, false},
+
+		// Empty cells only equal other empty cells and "".
+		{"median_to_merge == 0", false},
+		{`median_to_merge == ""`, true},
+		{"median_to_merge != 0", true},
+		{"median_to_merge < 1", false},
+		{"median_to_merge >= 1", false},
+		{"median_to_merge == unknown", true},
+
+		// Logic, which short-circuits.
+		{"true && false", false},
+		{"true || false", true},
+		{"!true", false},
+		{"!!true", true},
+		{"commits > 5 && deletions < 100", true},
+		{"commits > 50 || deletions < 100", true},
+		{"commits > 50 && additions / 0 > 1", false},
+		{"commits > 5 || additions / 0 > 1", true},
+		{"!(commits > 5) || prs_merged == 0", true},
+	}
+	for _, tt := range tests {
+		e, err := parseExpr(tt.source)
+		if err != nil {
+			t.Errorf("parseExpr(%q): %v", tt.source, err)
+			continue
+		}
+		got, err := e.eval(row)
+		if err != nil {
+			t.Errorf("eval(%q): %v", tt.source, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("eval(%q) = %#v, want %#v", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestParseExprIdents(t *testing.T) {
+	e, err := parseExpr(`Net_Lines > 0 && repo =~ "x" && true`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"netlines", "repository"}; !slices.Equal(e.idents, want) {
+		t.Errorf("idents = %q, want %q", e.idents, want)
+	}
+}
+
+func TestParseExprErrors(t *testing.T) {
+	for _, source := range []string{
+		"",
+		"1 +",
+		"(1 + 2",
+		"1 + 2)",
+		"1 2",
+		`"unterminated`,
+		"commits # 2",
+		"1..2",
+		"commits == == 2",
+		`repo =~ "["`,
+
+		// Operands of the wrong kind.
+		"contributor + 1",
+		"-repo",
+		`"a" * 2`,
+		"true + 1",
+		"(commits > 1) - 1",
+		"commits && true",
+		"false || commits",
+		"!commits",
+		"!1",
+		`"yes" || true`,
+	} {
+		if _, err := parseExpr(source); err == nil {
+			t.Errorf("parseExpr(%q) succeeded, want an error", source)
+		}
+	}
+}
+
+func TestParseExprCondition(t *testing.T) {
+	tests := []struct {
+		source    string
+		condition bool
+	}{
+		{"commits >= 5", true},
+		{`repo =~ "x" && !(commits < 1)`, true},
+		{"true", true},
+		{"(commits > 1)", true},
+		{"commits", false},
+		{"commits + 1", false},
+		{"repo", false},
+		{`"yes"`, false},
+	}
+	for _, tt := range tests {
+		e, err := parseExpr(tt.source)
+		if err != nil {
+			t.Errorf("parseExpr(%q): %v", tt.source, err)
+			continue
+		}
+		if e.condition != tt.condition {
+			t.Errorf("parseExpr(%q).condition = %v, want %v", tt.source, e.condition, tt.condition)
+		}
+		if _, err := parseCondition(tt.source); (err == nil) != tt.condition {
+			t.Errorf("parseCondition(%q) = %v, want ok %v", tt.source, err, tt.condition)
+		}
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	// Columns the parser does not know to hold text fail when they do.
+	row := testRow(map[string]string{"contributor": "(", "label": "bug"})
+	for _, source := range []string{
+		"label + 1",
+		"-label",
+		"label * 2 > 1",
+		`repo =~ contributor`,
+	} {
+		e, err := parseExpr(source)
+		if err != nil {
+			t.Errorf("parseExpr(%q): %v", source, err)
+			continue
+		}
+		if _, err := e.eval(row); err == nil {
+			t.Errorf("eval(%q) succeeded, want an error", source)
+		}
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		v    any
+		want string
+	}{
+		{nil, ""},
+		{3.0, "3"},
+		{2.0 / 3, "0.67"},
+		{-1.005, "-1"},
+		{true, "true"},
+		{"text", "text"},
+	}
+	for _, tt := range tests {
+		if got := formatValue(tt.v); got != tt.want {
+			t.Errorf("formatValue(%#v) = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
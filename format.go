@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// Output formats of the commands that print a table.
+const (
+	formatTable    = "table"
+	formatCSV      = "csv"
+	formatMarkdown = "md"
+)
+
+// writeFormatted writes a header and its records to w in the format.
+func writeFormatted(w io.Writer, format string, header []string, records [][]string) error {
+	switch format {
+	case formatCSV:
+		cw := csv.NewWriter(w)
+		cw.Write(header)
+		cw.WriteAll(records)
+		return cw.Error()
+	case formatMarkdown:
+		return writeMarkdown(w, header, records)
+	case formatTable:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(header, "\t"))
+		for _, record := range records {
+			fmt.Fprintln(tw, strings.Join(record, "\t"))
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("invalid format %q: want table, csv or md", format)
+	}
+}
+
+// writeMarkdown writes a header and its records as a Markdown table.
+func writeMarkdown(w io.Writer, header []string, records [][]string) error {
+	escape := strings.NewReplacer("|", `\|`, "\n", " ")
+	row := func(values []string) string {
+		cells := make([]string, len(values))
+		for i, v := range values {
+			cells[i] = escape.Replace(v)
+		}
+		return "| " + strings.Join(cells, " | ") + " |\n"
+	}
+	separator := make([]string, len(header))
+	for i := range separator {
+		separator[i] = "---"
+	}
+	if _, err := io.WriteString(w, row(header)+row(separator)); err != nil {
+		return err
+	}
+	for _, record := range records {
+		if _, err := io.WriteString(w, row(record)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
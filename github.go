@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const githubAPI = "https://api.github.com"
+
+// statusError is returned for responses with an unexpected status code.
+type statusError int
+
+func (e statusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", int(e))
+}
+
+// rateLimitError is returned when GitHub refuses a request because the rate
+// limit is exhausted.
+type rateLimitError struct {
+	reset time.Time
+}
+
+func (e rateLimitError) Error() string {
+	if e.reset.IsZero() {
+		return "rate limit exceeded"
+	}
+	return fmt.Sprintf("rate limit exceeded until %s", e.reset.Format(time.TimeOnly))
+}
+
+// checkStatus returns the error for a response that is not 200 OK, telling
+// rate limits apart from other failures.
+func checkStatus(resp *http.Response) error {
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests,
+		resp.StatusCode == http.StatusForbidden && (resp.Header.Get("X-RateLimit-Remaining") == "0" || resp.Header.Get("Retry-After") != ""):
+		var e rateLimitError
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			e.reset = time.Now().Add(time.Duration(seconds) * time.Second)
+		} else if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			e.reset = time.Unix(reset, 0)
+		}
+		return e
+	}
+	return statusError(resp.StatusCode)
+}
+
+// transient reports whether err may not happen again on a later run: rate
+// limits, server errors and network failures. Repositories that fail so are
+// not recorded as processed, so that -resume fetches them again.
+func transient(err error) bool {
+	var rateLimit rateLimitError
+	var status statusError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &rateLimit), errors.As(err, &netErr):
+		return true
+	case errors.As(err, &status):
+		return status >= 500
+	}
+	return false
+}
+
+// maxRateLimitWait is how long getJSON waits for a rate limit to reset, e.g.
+// the per-minute limit of the search API, before giving up.
+const maxRateLimitWait = time.Minute
+
+// getJSON performs an authenticated GET against the GitHub API and decodes
+// the response body into v. It returns the URL of the next page advertised
+// in the Link header, or an empty string on the last page.
+func getJSON(client *http.Client, url, token string, v any) (string, error) {
+	for attempt := 1; ; attempt++ {
+		next, err := getJSONOnce(client, url, token, v)
+		var rateLimit rateLimitError
+		if errors.As(err, &rateLimit) && !rateLimit.reset.IsZero() && attempt < 3 {
+			if wait := time.Until(rateLimit.reset); wait <= maxRateLimitWait {
+				time.Sleep(max(wait, time.Second))
+				continue
+			}
+		}
+		return next, err
+	}
+}
+
+func getJSONOnce(client *http.Client, url, token string, v any) (string, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Add("Authorization", "Bearer "+token)
+	req.Header.Add("Accept", "application/vnd.github+json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if err := checkStatus(resp); err != nil {
+		return "", err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return "", err
+	}
+	return nextPageURL(resp.Header.Get("Link")), nil
+}
+
+type FileContent struct {
+	Content string `json:"content"`
+}
+
+// fetchFileContent reads a file from the default branch of a repository.
+// found is false when the file does not exist.
+func fetchFileContent(client *http.Client, owner, repo, path, token string) (content string, found bool, err error) {
+	var file FileContent
+	url := fmt.Sprintf("%s/repos/%s/%s/contents/%s", githubAPI, owner, repo, path)
+	_, err = getJSON(client, url, token, &file)
+	var status statusError
+	if errors.As(err, &status) && status == http.StatusNotFound {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(file.Content, "\n", ""))
+	if err != nil {
+		return "", false, fmt.Errorf("decoding %s: %w", path, err)
+	}
+	return string(data), true, nil
+}
+
+// nextPageURL extracts the rel="next" target from a GitHub Link header.
+func nextPageURL(link string) string {
+	for _, part := range strings.Split(link, ",") {
+		segments := strings.Split(part, ";")
+		if len(segments) < 2 {
+			continue
+		}
+		for _, param := range segments[1:] {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(segments[0]), "<>")
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// identity is the canonical person behind one or more logins or emails.
+type identity struct {
+	Person string
+	Name   string
+	Team   string
+}
+
+// identityMap maps lowercased logins and emails to the person they belong
+// to.
+type identityMap map[string]identity
+
+// loadIdentities reads an identity map file. Each line holds an alias (a
+// login or a commit email), the canonical person it belongs to, and
+// optionally their display name and team:
+//
+//	alias,person,name,team
+//	octocat,octocat,The Octocat,platform
+//	octocat-work,octocat
+//	octocat@example.com,octocat
+//
+// A header line starting with "alias" and lines starting with # are skipped.
+func loadIdentities(path string) (identityMap, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	identities := make(identityMap)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if strings.EqualFold(record[0], "alias") {
+			continue
+		}
+		if len(record) < 2 || record[0] == "" || record[1] == "" {
+			line, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("%s:%d: expected alias,person[,name[,team]]", path, line)
+		}
+		id := identity{Person: record[1]}
+		if len(record) > 2 {
+			id.Name = record[2]
+		}
+		if len(record) > 3 {
+			id.Team = record[3]
+		}
+		identities[strings.ToLower(record[0])] = id
+	}
+
+	// Fill in names and teams given on any line of a person, so that
+	// aliases only need to name the person.
+	people := make(map[string]identity)
+	for _, id := range identities {
+		p := people[id.Person]
+		p.Person = id.Person
+		if p.Name == "" {
+			p.Name = id.Name
+		}
+		if p.Team == "" {
+			p.Team = id.Team
+		}
+		people[id.Person] = p
+	}
+	for alias, id := range identities {
+		identities[alias] = people[id.Person]
+	}
+	for person, id := range people {
+		if _, ok := identities[strings.ToLower(person)]; !ok {
+			identities[strings.ToLower(person)] = id
+		}
+	}
+	return identities, nil
+}
+
+// lookup returns the person behind a row, matching its contributor first and
+// then the commit emails seen for it. Rows that match nothing are their own
+// person.
+func (m identityMap) lookup(r contributorRow) identity {
+	for _, alias := range append([]string{r.Contributor}, r.Emails...) {
+		if id, ok := m[strings.ToLower(alias)]; ok {
+			return id
+		}
+	}
+	return identity{Person: r.Contributor}
+}
+
+// merge combines the rows of a repository that belong to the same person,
+// keeping the original logins of each row for auditing.
+func (m identityMap) merge(rows []contributorRow) []contributorRow {
+	var merged []contributorRow
+	index := make(map[string]int)
+	for _, r := range rows {
+		id := m.lookup(r)
+		logins := r.Logins
+		if len(logins) == 0 {
+			logins = []string{r.Contributor}
+		}
+		r.Contributor, r.Name, r.Team, r.Logins = id.Person, id.Name, id.Team, logins
+
+		i, ok := index[id.Person]
+		if !ok {
+			index[id.Person] = len(merged)
+			merged = append(merged, r)
+			continue
+		}
+		merged[i].add(r)
+	}
+	for i := range merged {
+		slices.Sort(merged[i].Logins)
+		merged[i].Logins = slices.Compact(merged[i].Logins)
+	}
+	return merged
+}
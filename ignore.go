@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// commitIgnore decides which bulk-change commits are left out of the
+// contributor totals.
+type commitIgnore struct {
+	// revs are the commit hashes, or prefixes of them, to ignore.
+	revs []string
+	// maxFiles ignores commits touching more files, when not zero.
+	maxFiles int
+	reverts  bool
+}
+
+func (ig commitIgnore) active() bool {
+	return len(ig.revs) > 0 || ig.maxFiles > 0 || ig.reverts
+}
+
+func (ig commitIgnore) ignores(c Commit) bool {
+	for _, rev := range ig.revs {
+		if strings.HasPrefix(c.SHA, rev) {
+			return true
+		}
+	}
+	if ig.maxFiles > 0 && len(c.Files) > ig.maxFiles {
+		return true
+	}
+	return ig.reverts && isRevert(c.Commit.Message)
+}
+
+// isRevert reports whether a commit message is that of a git revert.
+func isRevert(message string) bool {
+	return strings.HasPrefix(message, `Revert "`) || strings.Contains(message, "This reverts commit ")
+}
+
+// loadIgnoreRevs reads a list of commit hashes in the format of
+// .git-blame-ignore-revs: one hash per line, with # starting a comment.
+func loadIgnoreRevs(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var revs []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		if rev := strings.ToLower(strings.TrimSpace(line)); rev != "" {
+			revs = append(revs, rev)
+		}
+	}
+	return revs, scanner.Err()
+}
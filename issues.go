@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Issue struct {
+	Number int `json:"number"`
+	User   struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	CreatedAt time.Time  `json:"created_at"`
+	ClosedAt  *time.Time `json:"closed_at"`
+	ClosedBy  *struct {
+		Login string `json:"login"`
+	} `json:"closed_by"`
+	PullRequest *struct{} `json:"pull_request"`
+}
+
+type IssueComment struct {
+	User struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	CreatedAt time.Time `json:"created_at"`
+	IssueURL  string    `json:"issue_url"`
+}
+
+// issueMetrics holds the issue activity of a contributor or, summed up, of a
+// whole repository. Close times are those of the issues that were closed.
+type issueMetrics struct {
+	Opened     int
+	Closed     int
+	Commented  int
+	CloseTimes []time.Duration
+}
+
+// fetchIssues lists the issues of a repository that were updated on or after
+// since. Pull requests, which the endpoint also returns, are left out, and
+// issues closed within the range are fetched individually to learn who
+// closed them.
+func fetchIssues(client *http.Client, owner, repo, token string, start, end time.Time) ([]Issue, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/issues?state=all&sort=updated&direction=desc&per_page=100&since=%s",
+		githubAPI, owner, repo, start.UTC().Format(time.RFC3339))
+	var issues []Issue
+	for url != "" {
+		var page []Issue
+		next, err := getJSON(client, url, token, &page)
+		if err != nil {
+			return nil, err
+		}
+		for _, issue := range page {
+			if issue.PullRequest == nil {
+				issues = append(issues, issue)
+			}
+		}
+		url = next
+	}
+
+	for i := range issues {
+		if issues[i].ClosedAt == nil || !inRange(*issues[i].ClosedAt, start, end) {
+			continue
+		}
+		url := fmt.Sprintf("%s/repos/%s/%s/issues/%d", githubAPI, owner, repo, issues[i].Number)
+		if _, err := getJSON(client, url, token, &issues[i]); err != nil {
+			return nil, err
+		}
+	}
+	return issues, nil
+}
+
+// fetchIssueComments lists the issue and pull request conversation comments
+// of a repository that were updated on or after since.
+func fetchIssueComments(client *http.Client, owner, repo, token string, since time.Time) ([]IssueComment, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/issues/comments?per_page=100&since=%s",
+		githubAPI, owner, repo, since.UTC().Format(time.RFC3339))
+	var comments []IssueComment
+	for url != "" {
+		var page []IssueComment
+		next, err := getJSON(client, url, token, &page)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, page...)
+		url = next
+	}
+	return comments, nil
+}
+
+func processIssues(issues []Issue, comments []IssueComment, start, end time.Time) (map[string]*issueMetrics, issueMetrics) {
+	metrics := make(map[string]*issueMetrics)
+	get := func(login string) *issueMetrics {
+		if metrics[login] == nil {
+			metrics[login] = &issueMetrics{}
+		}
+		return metrics[login]
+	}
+	var summary issueMetrics
+
+	isIssue := make(map[int]bool)
+	for _, issue := range issues {
+		isIssue[issue.Number] = true
+		if inRange(issue.CreatedAt, start, end) {
+			get(issue.User.Login).Opened++
+			summary.Opened++
+		}
+		if issue.ClosedAt != nil && inRange(*issue.ClosedAt, start, end) {
+			closeTime := issue.ClosedAt.Sub(issue.CreatedAt)
+			summary.Closed++
+			summary.CloseTimes = append(summary.CloseTimes, closeTime)
+			if issue.ClosedBy != nil {
+				m := get(issue.ClosedBy.Login)
+				m.Closed++
+				m.CloseTimes = append(m.CloseTimes, closeTime)
+			}
+		}
+	}
+
+	commented := make(map[string]map[int]bool)
+	repoCommented := make(map[int]bool)
+	for _, comment := range comments {
+		number, _ := strconv.Atoi(comment.IssueURL[strings.LastIndex(comment.IssueURL, "/")+1:])
+		if !isIssue[number] || !inRange(comment.CreatedAt, start, end) {
+			continue
+		}
+		login := comment.User.Login
+		if commented[login] == nil {
+			commented[login] = make(map[int]bool)
+		}
+		if !commented[login][number] {
+			commented[login][number] = true
+			get(login).Commented++
+		}
+		if !repoCommented[number] {
+			repoCommented[number] = true
+			summary.Commented++
+		}
+	}
+	return metrics, summary
+}
+
+// joinIssues attaches issue metrics to the contributor rows of a repository
+// by login.
+func joinIssues(rows *repoRows, metrics map[string]*issueMetrics) {
+	for _, login := range sortedKeys(metrics) {
+		rows.get(login).Issues = *metrics[login]
+	}
+}
+
+func (m *issueMetrics) add(other issueMetrics) {
+	m.Opened += other.Opened
+	m.Closed += other.Closed
+	m.Commented += other.Commented
+	m.CloseTimes = append(m.CloseTimes, other.CloseTimes...)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"path"
+	"strings"
+)
+
+// otherLanguage collects files of no known language.
+const otherLanguage = "Other"
+
+// languagesByExtension maps lowercased file extensions to languages, named
+// as in GitHub linguist.
+var languagesByExtension = map[string]string{
+	".go":         "Go",
+	".ts":         "TypeScript",
+	".tsx":        "TypeScript",
+	".mts":        "TypeScript",
+	".cts":        "TypeScript",
+	".js":         "JavaScript",
+	".jsx":        "JavaScript",
+	".mjs":        "JavaScript",
+	".cjs":        "JavaScript",
+	".tf":         "HCL",
+	".tfvars":     "HCL",
+	".hcl":        "HCL",
+	".py":         "Python",
+	".rb":         "Ruby",
+	".rs":         "Rust",
+	".java":       "Java",
+	".kt":         "Kotlin",
+	".kts":        "Kotlin",
+	".scala":      "Scala",
+	".swift":      "Swift",
+	".m":          "Objective-C",
+	".c":          "C",
+	".h":          "C",
+	".cc":         "C++",
+	".cpp":        "C++",
+	".cxx":        "C++",
+	".hpp":        "C++",
+	".cs":         "C#",
+	".fs":         "F#",
+	".php":        "PHP",
+	".ex":         "Elixir",
+	".exs":        "Elixir",
+	".erl":        "Erlang",
+	".hs":         "Haskell",
+	".clj":        "Clojure",
+	".dart":       "Dart",
+	".lua":        "Lua",
+	".r":          "R",
+	".sh":         "Shell",
+	".bash":       "Shell",
+	".zsh":        "Shell",
+	".ps1":        "PowerShell",
+	".sql":        "SQL",
+	".proto":      "Protocol Buffer",
+	".graphql":    "GraphQL",
+	".gql":        "GraphQL",
+	".html":       "HTML",
+	".htm":        "HTML",
+	".css":        "CSS",
+	".scss":       "SCSS",
+	".sass":       "Sass",
+	".less":       "Less",
+	".vue":        "Vue",
+	".svelte":     "Svelte",
+	".yaml":       "YAML",
+	".yml":        "YAML",
+	".json":       "JSON",
+	".toml":       "TOML",
+	".xml":        "XML",
+	".md":         "Markdown",
+	".mdx":        "MDX",
+	".rst":        "reStructuredText",
+	".nix":        "Nix",
+	".bzl":        "Starlark",
+	".gradle":     "Gradle",
+	".mk":         "Makefile",
+	".dockerfile": "Dockerfile",
+}
+
+// languagesByName maps lowercased file names without a telling extension to
+// languages.
+var languagesByName = map[string]string{
+	"dockerfile":  "Dockerfile",
+	"makefile":    "Makefile",
+	"gnumakefile": "Makefile",
+	"jenkinsfile": "Groovy",
+	"gemfile":     "Ruby",
+	"rakefile":    "Ruby",
+	"build":       "Starlark",
+	"build.bazel": "Starlark",
+	"workspace":   "Starlark",
+}
+
+// languageOf returns the language of a file, judged by its name.
+func languageOf(file string) string {
+	name := strings.ToLower(path.Base(file))
+	if lang, ok := languagesByName[name]; ok {
+		return lang
+	}
+	if strings.HasPrefix(name, "dockerfile.") {
+		return "Dockerfile"
+	}
+	if lang, ok := languagesByExtension[path.Ext(name)]; ok {
+		return lang
+	}
+	return otherLanguage
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"log/slog"
@@ -15,8 +14,11 @@ import (
 )
 
 type ContributorStats struct {
-	Author struct {
+	// Author is nil for commits whose email is not linked to a GitHub
+	// account.
+	Author *struct {
 		Login string `json:"login"`
+		Type  string `json:"type"`
 	} `json:"author"`
 	Weeks []struct {
 		Week      int64 `json:"w"`
@@ -27,7 +29,39 @@ type ContributorStats struct {
 }
 
 func main() {
-	p := tea.NewProgram(newInputModel())
+	if len(os.Args) > 1 {
+		var run func(args []string) error
+		switch os.Args[1] {
+		case "cache":
+			run = runCache
+		case "history":
+			run = runHistory
+		case "trend":
+			run = runTrend
+		case "diff":
+			run = runDiff
+		case "merge":
+			run = runMerge
+		}
+		if run != nil {
+			if err := run(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	opts, err := parseOptions()
+	if err != nil {
+		slog.Error("Invalid options", "err", err)
+		os.Exit(1)
+	}
+	var outputs []string
+	for _, o := range opts.outputs {
+		outputs = append(outputs, o.path)
+	}
+	p := tea.NewProgram(newInputModel(outputs, opts.location))
 	model, err := p.Run()
 	if err != nil {
 		slog.Error("TUI input error", "err", err)
@@ -37,10 +71,10 @@ func main() {
 	if inp.githubToken != "" {
 		os.Setenv("GITHUB_TOKEN", inp.githubToken)
 	}
-	runProcessing(inp.startDate, inp.endDate, inp.outputPath, inp.reposPath)
+	runProcessing(inp.startDate, inp.endDate, inp.outputPath, inp.reposPath, opts)
 }
 
-func runProcessing(startDate, endDate, out, reposPath string) {
+func runProcessing(startDate, endDate, out, reposPath string, opts options) {
 	// Read repositories file:
 	data, err := os.ReadFile(reposPath)
 	if err != nil {
@@ -56,47 +90,139 @@ func runProcessing(startDate, endDate, out, reposPath string) {
 		}
 	}
 
-	// Setup output generation and HTTP client:
-	parsedStart, parsedEnd := parseDates(startDate, endDate)
-	outputFile, err := os.Create("output.csv")
+	outputs := opts.outputs
+	if len(outputs) == 0 {
+		// The prompted path picks its format like -out does.
+		o, err := parseOutput(out)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		outputs = []output{o}
+	}
+	// The checkpoint and snapshot go by the first output:
+	out = outputs[0].path
+
+	// Setup HTTP client and fetch:
+	parsedStart, parsedEnd, err := parseDates(startDate, endDate, time.Now().In(opts.location))
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error creating output file: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
-	defer outputFile.Close()
-	writer := csv.NewWriter(outputFile)
-	writer.Write([]string{"Repository", "Contributor", "Additions", "Deletions", "Commits", "StartDate", "EndDate"})
-	defer writer.Flush()
-	client := &http.Client{}
+	if opts.compare && opts.baselineStart.IsZero() {
+		opts.baselineStart, opts.baselineEnd = precedingPeriod(parsedStart, parsedEnd)
+	}
+	client := newClient(opts)
+	if opts.teamsOrg != "" {
+		if opts.teams == nil {
+			opts.teams = make(teamMap)
+		}
+		if err := fetchOrgTeams(client, opts.teamsOrg, os.Getenv("GITHUB_TOKEN"), opts.teams); err != nil {
+			fmt.Fprintf(os.Stderr, "Error fetching teams of %s: %v\n", opts.teamsOrg, err)
+			os.Exit(1)
+		}
+	}
 
-	processing := newProcessingModel(repos, parsedStart, parsedEnd, out, client, writer)
+	processing := newProcessingModel(repos, parsedStart, parsedEnd, out, client, opts)
+	processing.checkpoint = checkpoint{Start: parsedStart, End: parsedEnd, Repos: repos, Flags: resultFlags()}
+	if opts.resume {
+		saved, err := loadCheckpoint(checkpointPath(out))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading checkpoint: %v\n", err)
+			os.Exit(1)
+		}
+		if !saved.sameRun(processing.checkpoint) {
+			fmt.Fprintf(os.Stderr, "Error: %s was saved by a run with a different range, repositories or flags\n", checkpointPath(out))
+			os.Exit(1)
+		}
+		processing.current, processing.results = saved.Completed, saved.Results
+	}
 	p := tea.NewProgram(processing)
-	if _, err := p.Run(); err != nil {
+	model, err := p.Run()
+	if err != nil {
 		// Error running processing TUI
 	}
-}
+	processed := model.(processingModel)
 
-func parseDates(startStr, endStr string) (time.Time, time.Time) {
-	loc := time.UTC
-	now := time.Now().In(loc)
-	var start time.Time
-
-	if endStr == "" {
-		endStr = now.Format("2006-01-02")
+	// Write what was collected, even if processing was interrupted:
+	if err := writeReport(outputs, processed.results, parsedStart, parsedEnd, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing output file: %v\n", err)
+		os.Exit(1)
+	}
+	if processed.err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", processed.err)
+	}
+	if processed.current < len(repos) {
+		fmt.Fprintf(os.Stderr, "Processed %d of %d repositories; run again with -resume to continue.\n", processed.current, len(repos))
+	} else {
+		os.Remove(checkpointPath(out))
+		if !opts.noArchive {
+			now := time.Now().UTC()
+			s := snapshot{
+				ID:      now.Format("20060102T150405Z"),
+				Created: now,
+				Start:   parsedStart,
+				End:     parsedEnd,
+				Output:  out,
+				Repos:   repos,
+				Flags:   processing.checkpoint.Flags,
+				Results: processed.results,
+			}
+			if err := saveSnapshot(opts.archiveDir, s); err != nil {
+				fmt.Fprintf(os.Stderr, "Error saving snapshot: %v\n", err)
+			}
+		}
 	}
-	end, _ := time.ParseInLocation("2006-01-02", endStr, loc)
-	end = end.Add(23*time.Hour + 59*time.Minute + 59*time.Second)
+}
 
+// parseDates resolves the start and end date expressions, see dateRange, to
+// the start of the first day and the end of the last day of the range. An
+// empty end date is the end of the period the start date names, or today
+// for a single day; an empty start date is a month before the end. Dates are
+// in the location of now.
+func parseDates(startStr, endStr string, now time.Time) (time.Time, time.Time, error) {
+	var start, end time.Time
+	if startStr != "" {
+		first, last, err := dateRange(startStr, now)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("start date: %w", err)
+		}
+		start = first
+		if endStr == "" && !last.Equal(first) {
+			end = endOfDay(last)
+		}
+	}
+	if endStr != "" {
+		_, last, err := dateRange(endStr, now)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("end date: %w", err)
+		}
+		end = endOfDay(last)
+	} else if end.IsZero() {
+		end = endOfDay(now)
+	}
 	if startStr == "" {
 		start = end.AddDate(0, -1, 0)
-	} else {
-		start, _ = time.ParseInLocation("2006-01-02", startStr, loc)
 	}
-	return start.UTC(), end.UTC()
+	if end.Before(start) {
+		return time.Time{}, time.Time{}, fmt.Errorf("the end date %s is before the start date %s", end.Format("2006-01-02"), start.Format("2006-01-02"))
+	}
+	return start, end, nil
 }
 
 func fetchContributorStats(client *http.Client, owner, repo, token string) ([]ContributorStats, error) {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/stats/contributors", owner, repo)
+	var stats []ContributorStats
+	url := fmt.Sprintf("%s/repos/%s/%s/stats/contributors", githubAPI, owner, repo)
+	if err := fetchStats(client, url, token, &stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// fetchStats fetches one of the repository statistics endpoints into v,
+// polling while GitHub is still computing the statistics. v is left as is
+// when the repository is empty.
+func fetchStats(client *http.Client, url, token string, v any) error {
 	req, _ := http.NewRequest("GET", url, nil)
 	req.Header.Add("Authorization", "Bearer "+token)
 	req.Header.Add("Accept", "application/vnd.github+json")
@@ -104,29 +230,34 @@ func fetchContributorStats(client *http.Client, owner, repo, token string) ([]Co
 	for {
 		resp, err := client.Do(req)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		defer resp.Body.Close()
 
 		switch resp.StatusCode {
 		case http.StatusOK:
-			var stats []ContributorStats
-			if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
-				return nil, err
-			}
-			return stats, nil
+			err := json.NewDecoder(resp.Body).Decode(v)
+			resp.Body.Close()
+			return err
+		case http.StatusNoContent:
+			resp.Body.Close()
+			return nil
 		case http.StatusAccepted:
+			resp.Body.Close()
 			time.Sleep(1 * time.Second)
 			continue
 		default:
-			return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+			resp.Body.Close()
+			return checkStatus(resp)
 		}
 	}
 }
 
-func processStats(stats []ContributorStats, repo string, start, end time.Time, writer *csv.Writer, format string) {
+func processStats(stats []ContributorStats, repo string, start, end time.Time) []contributorRow {
+	var rows []contributorRow
+	unlinked := -1
 	for _, contributor := range stats {
 		var totalAdditions, totalDeletions, totalCommits int
+		var weeks weeklyActivity
 
 		for _, week := range contributor.Weeks {
 			weekStart := time.Unix(week.Week, 0).UTC()
@@ -139,33 +270,54 @@ func processStats(stats []ContributorStats, repo string, start, end time.Time, w
 			totalAdditions += week.Additions
 			totalDeletions += week.Deletions
 			totalCommits += week.Commits
+			weeks = weeks.add(week.Week, weekCounts{week.Additions, week.Deletions, week.Commits})
 		}
 
 		if totalAdditions == 0 && totalDeletions == 0 && totalCommits == 0 {
 			continue
 		}
 
-		writer.Write([]string{
-			repo,
-			contributor.Author.Login,
-			fmt.Sprintf("%d", totalAdditions),
-			fmt.Sprintf("%d", totalDeletions),
-			fmt.Sprintf("%d", totalCommits),
-			start.Format("2006-01-02"),
-			end.Format("2006-01-02"),
-		})
+		row := contributorRow{
+			Repository: repo,
+			Additions:  totalAdditions,
+			Deletions:  totalDeletions,
+			Commits:    totalCommits,
+			Weeks:      weeks,
+		}
+		if contributor.Author == nil {
+			// GitHub gives no way to tell unlinked authors apart here, so
+			// they all share one row.
+			row.Contributor = unlinkedContributor
+			if unlinked >= 0 {
+				rows[unlinked].add(row)
+				continue
+			}
+			unlinked = len(rows)
+		} else {
+			row.Contributor = contributor.Author.Login
+			row.Bot = contributor.Author.Type == "Bot"
+		}
+		rows = append(rows, row)
 	}
+	return rows
 }
 
 type (
-	repoProcessedMsg string
-	TickMsg          time.Time
+	// repoProcessedMsg reports that a repository is finished. result is nil
+	// when nothing could be collected for it, and err is set when it failed
+	// in a way a later run may not, so that it is not finished after all.
+	repoProcessedMsg struct {
+		repo   string
+		result *repoResult
+		err    error
+	}
+	TickMsg time.Time
 )
 
 func processRepo(repo string) tea.Cmd {
 	return func() tea.Msg {
 		time.Sleep(2 * time.Second)
-		return repoProcessedMsg(repo)
+		return repoProcessedMsg{repo: repo}
 	}
 }
 
@@ -179,10 +331,15 @@ type processingModel struct {
 	end     time.Time
 	out     string
 	client  *http.Client
-	writer  *csv.Writer
+	opts    options
+	results []repoResult
+	// checkpoint is saved after every repository.
+	checkpoint checkpoint
+	// err stopped processing before every repository was processed.
+	err error
 }
 
-func newProcessingModel(repos []string, start, end time.Time, out string, client *http.Client, writer *csv.Writer) processingModel {
+func newProcessingModel(repos []string, start, end time.Time, out string, client *http.Client, opts options) processingModel {
 	s := spinner.New()
 	s.Spinner = spinner.Dot
 	return processingModel{
@@ -194,13 +351,18 @@ func newProcessingModel(repos []string, start, end time.Time, out string, client
 		end:     end,
 		out:     out,
 		client:  client,
-		writer:  writer,
+		opts:    opts,
 	}
 }
 
 func (m processingModel) Init() tea.Cmd {
-	if len(m.repos) > 0 {
+	switch {
+	case m.current < len(m.repos):
 		return tea.Batch(m.spinner.Tick, m.processCurrentRepo())
+	case m.current > 0:
+		// Resumed from the checkpoint of a run that already processed
+		// every repository.
+		return tea.Quit
 	}
 	return m.spinner.Tick
 }
@@ -219,8 +381,25 @@ func (m processingModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.spinner, cmd = m.spinner.Update(msg)
 		return m, cmd
 	case repoProcessedMsg:
-		m.message = fmt.Sprintf("Processed repository: %s", msg)
+		if msg.err != nil {
+			// The repository is left for -resume, and the checkpoint saved
+			// in case it was the first.
+			m.err, m.done = msg.err, true
+			m.checkpoint.Completed, m.checkpoint.Results = m.current, m.results
+			if err := m.checkpoint.save(checkpointPath(m.out)); err != nil {
+				fmt.Fprintf(os.Stderr, "Error saving checkpoint: %v\n", err)
+			}
+			return m, tea.Quit
+		}
+		m.message = fmt.Sprintf("Processed repository: %s", msg.repo)
+		if msg.result != nil {
+			m.results = append(m.results, *msg.result)
+		}
 		m.current++
+		m.checkpoint.Completed, m.checkpoint.Results = m.current, m.results
+		if err := m.checkpoint.save(checkpointPath(m.out)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error saving checkpoint: %v\n", err)
+		}
 		if m.current < len(m.repos) {
 			return m, tea.Batch(tea.Tick(time.Second, func(t time.Time) tea.Msg {
 				return TickMsg(t)
@@ -236,6 +415,9 @@ func (m processingModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 func (m processingModel) View() string {
 	if m.done {
+		if m.err != nil {
+			return fmt.Sprintf("Stopped at repository %d/%d: %v\n", m.current+1, len(m.repos), m.err)
+		}
 		return "All repositories processed.\n"
 	}
 	currentRepo := ""
@@ -251,15 +433,12 @@ func (m processingModel) processCurrentRepo() tea.Cmd {
 		parts := strings.Split(repo, "/")
 		if len(parts) != 2 {
 			fmt.Fprintf(os.Stderr, "Invalid repository format: %s\n", repo)
-			return repoProcessedMsg(repo)
+			return repoProcessedMsg{repo: repo}
 		}
-		owner, repoName := parts[0], parts[1]
-		stats, err := fetchContributorStats(m.client, owner, repoName, os.Getenv("GITHUB_TOKEN"))
+		result, err := collectRepo(m.client, parts[0], parts[1], os.Getenv("GITHUB_TOKEN"), m.start, m.end, m.opts)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error fetching stats for %s: %v\n", repo, err)
-			return repoProcessedMsg(repo)
+			return repoProcessedMsg{repo: repo, err: err}
 		}
-		processStats(stats, repo, m.start, m.end, m.writer, m.out)
-		return repoProcessedMsg(repo)
+		return repoProcessedMsg{repo: repo, result: &result}
 	}
 }
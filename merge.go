@@ -0,0 +1,296 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Groupings of the merge subcommand.
+const (
+	byContributor = "contributor"
+	byRepo        = "repo"
+	byTeam        = "team"
+)
+
+// countFields are the metrics of the contributor report that are sums, and
+// can therefore be read back from an export and added up again. Medians
+// cannot, as the samples behind them are not exported.
+var countFields = map[string]func(r *contributorRow) *int{
+	"Additions":               func(r *contributorRow) *int { return &r.Additions },
+	"Deletions":               func(r *contributorRow) *int { return &r.Deletions },
+	"Commits":                 func(r *contributorRow) *int { return &r.Commits },
+	"ExcludedCommits":         func(r *contributorRow) *int { return &r.ExcludedCommits },
+	"ExcludedLines":           func(r *contributorRow) *int { return &r.ExcludedLines },
+	"PRsOpened":               func(r *contributorRow) *int { return &r.PRs.Opened },
+	"PRsMerged":               func(r *contributorRow) *int { return &r.PRs.Merged },
+	"PRsClosedUnmerged":       func(r *contributorRow) *int { return &r.PRs.ClosedUnmerged },
+	"ReviewsApproved":         func(r *contributorRow) *int { return &r.Reviews.Approvals },
+	"ReviewsChangesRequested": func(r *contributorRow) *int { return &r.Reviews.ChangesRequested },
+	"ReviewsCommented":        func(r *contributorRow) *int { return &r.Reviews.Comments },
+	"ReviewComments":          func(r *contributorRow) *int { return &r.Reviews.ReviewComments },
+	"PRsReviewed":             func(r *contributorRow) *int { return &r.Reviews.PRsReviewed },
+	"IssuesOpened":            func(r *contributorRow) *int { return &r.Issues.Opened },
+	"IssuesClosed":            func(r *contributorRow) *int { return &r.Issues.Closed },
+	"IssuesCommented":         func(r *contributorRow) *int { return &r.Issues.Commented },
+}
+
+// rowsFromTable reads the contributor or team rows of an export back. The
+// overall rows of team exports are skipped when the file also has the rows
+// of the individual repositories, which they repeat. Overall rows written
+// by merge are kept, as they are all there is.
+func rowsFromTable(t table) ([]contributorRow, error) {
+	col := func(name string) int { return slices.Index(t.header, name) }
+	repo := col("Repository")
+	if repo < 0 || (col("Contributor") < 0 && col("Team") < 0) {
+		return nil, fmt.Errorf("not a contributor or team report: no Repository and Contributor or Team columns")
+	}
+	skipOverall := col("Contributor") < 0 && slices.ContainsFunc(t.records, func(record []string) bool {
+		return record[repo] != allRepositories
+	})
+
+	var rows []contributorRow
+	for _, record := range t.records {
+		if skipOverall && record[repo] == allRepositories {
+			continue
+		}
+		r := contributorRow{Repository: record[repo]}
+		for i, name := range t.header {
+			value := record[i]
+			switch name {
+			case "Contributor":
+				r.Contributor = value
+			case "Name":
+				r.Name = value
+			case "Team":
+				r.Team = value
+			case "Logins", "Members":
+				r.Logins = strings.Fields(value)
+			case "StartDate", "EndDate":
+				date, err := time.Parse("2006-01-02", value)
+				if err != nil {
+					return nil, fmt.Errorf("invalid %s %q", name, value)
+				}
+				if name == "StartDate" {
+					r.Start = date
+				} else {
+					r.End = date
+				}
+			default:
+				field, ok := countFields[name]
+				if !ok {
+					continue
+				}
+				n, err := strconv.Atoi(value)
+				if err != nil {
+					return nil, fmt.Errorf("invalid %s %q", name, value)
+				}
+				*field(&r) = n
+			}
+		}
+		rows = append(rows, r)
+	}
+	return rows, nil
+}
+
+// runMerge implements the merge subcommand, which combines several exports,
+// e.g. run by different teams, and sums them up again:
+//
+//	ghstats merge [-by contributor|repo|team] [-teams FILE] [-format csv|table|md] [-o FILE] a.csv b.csv...
+func runMerge(args []string) error {
+	flags := flag.NewFlagSet("merge", flag.ContinueOnError)
+	by := flags.String("by", byContributor, "sum up the rows per contributor, repo or team")
+	teamsPath := flags.String("teams", "", "team mapping `file` of login,team lines for -by team")
+	format := flags.String("format", formatCSV, "output `format`: csv, table or md")
+	out := flags.String("o", "", "write to the `file` instead of standard output")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	switch *by {
+	case byContributor, byRepo, byTeam:
+	default:
+		return fmt.Errorf("invalid -by value %q: want contributor, repo or team", *by)
+	}
+	if flags.NArg() == 0 {
+		return fmt.Errorf("usage: ghstats merge [-by contributor|repo|team] a.csv b.csv...")
+	}
+	teams := make(teamMap)
+	if *teamsPath != "" {
+		var err error
+		if teams, err = loadTeams(*teamsPath); err != nil {
+			return fmt.Errorf("loading teams: %w", err)
+		}
+	}
+
+	// Rows of the same repository, contributor or team and range in several
+	// files are counted once. Rows are identified by the contributor, or by
+	// the team in team exports, so that a contributor who changed teams is
+	// still the same.
+	type rowKey struct {
+		repo, who  string
+		start, end time.Time
+	}
+	keyOf := func(r contributorRow) rowKey {
+		who := r.Contributor
+		if who == "" {
+			who = "team " + r.Team
+		}
+		return rowKey{r.Repository, who, r.Start, r.End}
+	}
+	seen := make(map[rowKey]contributorRow)
+	source := make(map[rowKey]string)
+	var rows []contributorRow
+	present := make(map[string]bool)
+	duplicates, conflicts := 0, 0
+	for _, path := range flags.Args() {
+		t, err := readTable(path)
+		if err != nil {
+			return err
+		}
+		if *by == byContributor && !slices.Contains(t.header, "Contributor") {
+			return fmt.Errorf("%s has no Contributor column: merge team exports -by team or -by repo", path)
+		}
+		fileRows, err := rowsFromTable(t)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if len(fileRows) == 0 {
+			fmt.Fprintf(os.Stderr, "%s has no rows to merge\n", path)
+		}
+		for _, name := range t.header {
+			present[name] = true
+		}
+		for _, r := range fileRows {
+			k := keyOf(r)
+			if first, ok := seen[k]; ok {
+				duplicates++
+				if !slices.Equal(renderCounts(first), renderCounts(r)) {
+					conflicts++
+				}
+				continue
+			}
+			seen[k] = r
+			source[k] = path
+			rows = append(rows, r)
+		}
+	}
+	if duplicates > 0 {
+		fmt.Fprintf(os.Stderr, "Skipped %d duplicate rows, %d of them with different numbers than the row kept\n", duplicates, conflicts)
+	}
+
+	// Rows whose ranges overlap without being the same cannot be told apart
+	// from the sums alone, so the overlap would be counted twice. An overall
+	// row, e.g. from an earlier merge, overlaps every repository.
+	byWho := make(map[string][]rowKey)
+	for _, r := range rows {
+		k := keyOf(r)
+		for _, other := range byWho[k.who] {
+			sameRepo := k.repo == other.repo || k.repo == allRepositories || other.repo == allRepositories
+			if sameRepo && !k.start.After(other.end) && !other.start.After(k.end) {
+				return fmt.Errorf("%s and %s overlap: both count %s in %s from %s to %s, which would be counted twice",
+					source[other], source[k], k.who, k.repo,
+					later(k.start, other.start).Format("2006-01-02"), earlier(k.end, other.end).Format("2006-01-02"))
+			}
+		}
+		byWho[k.who] = append(byWho[k.who], k)
+	}
+
+	totals := make(map[string]*contributorRow)
+	var order []string
+	addTo := func(key string, r contributorRow) {
+		t, ok := totals[key]
+		if !ok {
+			t = &contributorRow{Repository: allRepositories, Start: r.Start, End: r.End}
+			switch *by {
+			case byContributor:
+				t.Contributor = key
+			case byRepo:
+				t.Repository, t.Contributor = key, repoTotal
+			case byTeam:
+				t.Team = key
+			}
+			totals[key] = t
+			order = append(order, key)
+		}
+		t.add(r)
+		if r.Start.Before(t.Start) {
+			t.Start = r.Start
+		}
+		if r.End.After(t.End) {
+			t.End = r.End
+		}
+	}
+	for _, r := range rows {
+		switch *by {
+		case byContributor:
+			addTo(r.Contributor, r)
+		case byRepo:
+			addTo(r.Repository, r)
+		case byTeam:
+			for _, team := range teams.teamsOf(r) {
+				addTo(team, r)
+			}
+		}
+	}
+	merged := make([]contributorRow, len(order))
+	for i, key := range order {
+		merged[i] = *totals[key]
+	}
+
+	all := slices.Concat(baseColumns, pullColumns, reviewColumns, excludedColumns, issueColumns, derivedColumns)
+	named := func(name string) column {
+		return all[slices.IndexFunc(all, func(c column) bool { return c.name == name })]
+	}
+	columns := []column{named("Repository"), named("Contributor")}
+	if *by == byTeam {
+		columns[1] = column{"Team", func(r contributorRow) string { return r.Team }}
+	}
+	for _, c := range all {
+		if _, ok := countFields[c.name]; ok && present[c.name] {
+			columns = append(columns, c)
+		}
+	}
+	for _, name := range []string{"Net", "Churn", "LinesPerCommit", "StartDate", "EndDate"} {
+		columns = append(columns, named(name))
+	}
+	header, records := renderRows(columns, merged)
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return writeFormatted(w, *format, header, records)
+}
+
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func earlier(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// renderCounts returns the sums of a row, to tell whether two rows agree.
+func renderCounts(r contributorRow) []int {
+	var counts []int
+	for _, name := range sortedKeys(countFields) {
+		counts = append(counts, *countFields[name](&r))
+	}
+	return counts
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+	// The zone database is embedded, so that -tz works where the system
+	// has none, e.g. on Windows or in minimal containers.
+	_ "time/tzdata"
+)
+
+// options holds the settings given on the command line. Everything else is
+// collected interactively by inputModel.
+type options struct {
+	source    string
+	prs       bool
+	reviews   bool
+	issues    bool
+	dora      bool
+	repoStats bool
+
+	// doraEnvironment is the deployment environment -dora counts.
+	doraEnvironment string
+
+	codeOwners bool
+	languages  bool
+
+	pathFilter      pathFilter
+	defaultExcludes bool
+	gitAttributes   bool
+
+	ignoreRevsPath string
+	commitIgnore   commitIgnore
+
+	identitiesPath string
+	identities     identityMap
+
+	bots      string
+	botFilter botFilter
+
+	teamsPath string
+	teamsOrg  string
+	teamsOnly bool
+	teams     teamMap
+
+	// compare is set with -compare or a baseline range. Without one, the
+	// baseline is resolved to the preceding period once the range is known.
+	compare       bool
+	baselineStart time.Time
+	baselineEnd   time.Time
+
+	noCache bool
+	refresh bool
+
+	resume bool
+
+	archiveDir string
+	noArchive  bool
+
+	query rowQuery
+
+	// outputs are given with -out. Without, the output path is asked for.
+	outputs []output
+
+	// timeZone is the -tz value, and location the zone dates are given and
+	// written in.
+	timeZone string
+	location *time.Location
+}
+
+// teamRollup reports whether team rows are wanted, because teams were given
+// in a file, the identity map or a GitHub organization.
+func (opts options) teamRollup() bool {
+	if opts.teamsPath != "" || opts.teamsOrg != "" || opts.teamsOnly {
+		return true
+	}
+	for _, id := range opts.identities {
+		if id.Team != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Data sources for the line counts of the contributor rows.
+const (
+	// sourceStats uses GitHub's weekly contributor statistics.
+	sourceStats = "stats"
+	// sourceCommits fetches every commit in the range, which costs one
+	// request per commit but gives access to commit emails and files.
+	sourceCommits = "commits"
+)
+
+// stringList is a flag that can be given several times.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func parseOptions() (options, error) {
+	var opts options
+	flag.StringVar(&opts.source, "source", sourceStats, "where line counts come from: stats or commits")
+	flag.BoolVar(&opts.prs, "prs", false, "collect pull request metrics, which costs a request per pull request opened within the range")
+	flag.BoolVar(&opts.reviews, "reviews", false, "collect code review metrics, which costs a request per pull request updated within the range")
+	flag.BoolVar(&opts.issues, "issues", false, "collect issue activity metrics")
+	flag.BoolVar(&opts.dora, "dora", false, "write a DORA metrics section per repository")
+	flag.StringVar(&opts.doraEnvironment, "dora-environment", "production", "count deployments to the `environment` for -dora, or to every environment if empty")
+	flag.BoolVar(&opts.repoStats, "repo-stats", false, "write weekly, daily, participation and punch card sections per repository")
+	flag.BoolVar(&opts.codeOwners, "codeowners", false, "attribute changed lines to CODEOWNERS owners (needs -source commits)")
+	flag.BoolVar(&opts.languages, "languages", false, "break changed lines down by language (needs -source commits)")
+	flag.Var((*stringList)(&opts.pathFilter.include), "include-path", "only count lines in files matching the `pattern` (repeatable, needs -source commits)")
+	flag.Var((*stringList)(&opts.pathFilter.exclude), "exclude-path", "do not count lines in files matching the `pattern` (repeatable, needs -source commits)")
+	flag.BoolVar(&opts.defaultExcludes, "default-excludes", true, "do not count lines in vendored, generated, lock and snapshot files (with -source commits)")
+	flag.BoolVar(&opts.gitAttributes, "gitattributes", true, "do not count lines in files marked linguist-generated or linguist-vendored (with -source commits)")
+	flag.StringVar(&opts.ignoreRevsPath, "ignore-revs", "", "leave out the commits listed in the `file`, e.g. .git-blame-ignore-revs (needs -source commits)")
+	flag.IntVar(&opts.commitIgnore.maxFiles, "max-files", 0, "leave out commits touching more than `n` files (needs -source commits)")
+	flag.BoolVar(&opts.commitIgnore.reverts, "skip-reverts", false, "leave out revert commits (needs -source commits)")
+	flag.StringVar(&opts.identitiesPath, "identities", "", "identity map `file` merging logins and emails into people")
+	flag.StringVar(&opts.bots, "bots", botsKeep, "what to do with bot accounts: keep, exclude or separate")
+	flag.Var((*stringList)(&opts.botFilter.exclude), "exclude-login", "treat logins matching the glob `pattern` as bots (repeatable)")
+	flag.Var((*stringList)(&opts.botFilter.include), "include-login", "never treat logins matching the glob `pattern` as bots (repeatable)")
+	flag.StringVar(&opts.teamsPath, "teams", "", "team mapping `file` of login,team lines")
+	flag.StringVar(&opts.teamsOrg, "teams-org", "", "read teams and their members from the GitHub `organization`")
+	flag.BoolVar(&opts.teamsOnly, "teams-only", false, "write team rows instead of contributor rows")
+	flag.BoolVar(&opts.compare, "compare", false, "compare with the preceding period of equal length")
+	baselineStart := flag.String("baseline-start", "", "compare with a baseline period starting on `date`, e.g. 2024-01-15, 2024-Q1 or last-month")
+	baselineEnd := flag.String("baseline-end", "", "compare with a baseline period ending on `date`; defaults to the end of the period -baseline-start names")
+	flag.BoolVar(&opts.noCache, "no-cache", false, "neither read nor write the response cache")
+	flag.BoolVar(&opts.refresh, "refresh", false, "refetch everything, replacing the cached responses")
+	flag.BoolVar(&opts.resume, "resume", false, "continue an interrupted run from its checkpoint next to the output file")
+	flag.StringVar(&opts.archiveDir, "archive", defaultArchiveDir(), "store a snapshot of every completed run in the `directory`")
+	flag.BoolVar(&opts.noArchive, "no-archive", false, "do not store a snapshot of this run")
+	var where, columns stringList
+	var sort string
+	flag.Var(&where, "where", "only write rows matching the `expression`, e.g. 'commits >= 5 && repo =~ \"api-.*\"' (repeatable)")
+	flag.Var(&columns, "column", "add a computed column, e.g. 'ratio = deletions / additions' (repeatable)")
+	flag.StringVar(&sort, "sort", "", "sort rows by the comma-separated `columns`, descending when prefixed with -")
+	flag.IntVar(&opts.query.limit, "limit", 0, "write at most `n` rows")
+	var outs stringList
+	flag.Var(&outs, "out", "write the report to the `file`, in the format of its extension: csv, json, md, html or prom, or to PATH=TEMPLATE with a Go template (repeatable)")
+	flag.StringVar(&opts.timeZone, "tz", "UTC", "time `zone` dates are given and written in, e.g. Asia/Singapore, or Local for the system's")
+	flag.Parse()
+
+	var err error
+	if opts.location, err = time.LoadLocation(opts.timeZone); err != nil {
+		return opts, fmt.Errorf("invalid -tz: %w", err)
+	}
+
+	switch opts.source {
+	case sourceStats, sourceCommits:
+	default:
+		return opts, fmt.Errorf("invalid -source value %q: want stats or commits", opts.source)
+	}
+	if opts.codeOwners && opts.source != sourceCommits {
+		return opts, fmt.Errorf("-codeowners needs -source commits")
+	}
+	if opts.languages && opts.source != sourceCommits {
+		return opts, fmt.Errorf("-languages needs -source commits")
+	}
+	if len(opts.pathFilter.include)+len(opts.pathFilter.exclude) > 0 && opts.source != sourceCommits {
+		return opts, fmt.Errorf("-include-path and -exclude-path need -source commits")
+	}
+	if opts.ignoreRevsPath != "" {
+		revs, err := loadIgnoreRevs(opts.ignoreRevsPath)
+		if err != nil {
+			return opts, fmt.Errorf("loading ignored revisions: %w", err)
+		}
+		opts.commitIgnore.revs = revs
+	}
+	if opts.commitIgnore.active() && opts.source != sourceCommits {
+		return opts, fmt.Errorf("-ignore-revs, -max-files and -skip-reverts need -source commits")
+	}
+	if opts.defaultExcludes {
+		opts.pathFilter.exclude = append(opts.pathFilter.exclude, defaultExcludedPaths...)
+	}
+	switch opts.bots {
+	case botsExclude, botsSeparate, botsKeep:
+	default:
+		return opts, fmt.Errorf("invalid -bots value %q: want keep, exclude or separate", opts.bots)
+	}
+	if opts.bots == botsKeep && len(opts.botFilter.exclude)+len(opts.botFilter.include) > 0 {
+		return opts, fmt.Errorf("-exclude-login and -include-login need -bots exclude or separate")
+	}
+	for _, pattern := range slices.Concat(opts.botFilter.exclude, opts.botFilter.include) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return opts, fmt.Errorf("invalid login pattern %q: %w", pattern, err)
+		}
+	}
+
+	if *baselineStart != "" || *baselineEnd != "" {
+		if *baselineStart == "" {
+			return opts, fmt.Errorf("-baseline-end needs -baseline-start")
+		}
+		if opts.baselineStart, opts.baselineEnd, err = parseDates(*baselineStart, *baselineEnd, time.Now().In(opts.location)); err != nil {
+			return opts, fmt.Errorf("invalid baseline: %w", err)
+		}
+		opts.compare = true
+	}
+
+	for _, definition := range columns {
+		c, err := parseColumn(definition)
+		if err != nil {
+			return opts, err
+		}
+		opts.query.columns = append(opts.query.columns, c)
+	}
+	for _, source := range where {
+		e, err := parseCondition(source)
+		if err != nil {
+			return opts, err
+		}
+		opts.query.where = append(opts.query.where, e)
+	}
+	opts.query.sort = parseSort(sort)
+	if opts.query.limit < 0 {
+		return opts, fmt.Errorf("invalid -limit %d", opts.query.limit)
+	}
+
+	if opts.identitiesPath != "" {
+		identities, err := loadIdentities(opts.identitiesPath)
+		if err != nil {
+			return opts, fmt.Errorf("loading identities: %w", err)
+		}
+		opts.identities = identities
+	}
+	if opts.teamsPath != "" {
+		teams, err := loadTeams(opts.teamsPath)
+		if err != nil {
+			return opts, fmt.Errorf("loading teams: %w", err)
+		}
+		opts.teams = teams
+	}
+	for _, spec := range outs {
+		o, err := parseOutput(spec)
+		if err != nil {
+			return opts, err
+		}
+		opts.outputs = append(opts.outputs, o)
+	}
+
+	// Columns are only known once the metric sets and identities are.
+	columnsOf := contributorColumns
+	if opts.teamsOnly {
+		columnsOf = teamColumns
+	}
+	header, _ := renderRows(columnsOf(opts), nil)
+	if err := opts.query.check(header); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
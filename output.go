@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+)
+
+// Formats of the outputs, chosen by the extension of their path.
+const (
+	outputCSV        = "csv"
+	outputJSON       = "json"
+	outputMarkdown   = "md"
+	outputHTML       = "html"
+	outputPrometheus = "prom"
+	outputTemplate   = "template"
+)
+
+// output is a file the report is written to.
+type output struct {
+	path   string
+	format string
+	// template is set for the template format.
+	template *template.Template
+}
+
+// parseOutput parses an -out value: a path whose extension selects the
+// format, or PATH=TEMPLATE to write PATH with a Go template.
+func parseOutput(spec string) (output, error) {
+	if path, templatePath, ok := strings.Cut(spec, "="); ok {
+		tmpl, err := parseTemplate(templatePath)
+		if err != nil {
+			return output{}, fmt.Errorf("loading template: %w", err)
+		}
+		return output{path: path, format: outputTemplate, template: tmpl}, nil
+	}
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(spec)), ".")
+	switch format {
+	case outputCSV, outputJSON, outputMarkdown, outputHTML, outputPrometheus:
+		return output{path: spec, format: format}, nil
+	}
+	return output{}, fmt.Errorf("invalid output %q: want a .csv, .json, .md, .html or .prom file, or PATH=TEMPLATE", spec)
+}
+
+// writeOutput writes the report in the format of o:
+//
+//   - csv: the rows of the report, and every section in a file next to it.
+//   - json: the whole result model, as templates receive it.
+//   - md, html: the rows of the report and every section as tables.
+//   - prom: the metrics of the rows of the report in the Prometheus text
+//     format, e.g. for the node exporter's textfile collector.
+//   - template: the output of a Go template.
+func writeOutput(o output, results []repoResult, start, end time.Time, opts options) error {
+	switch o.format {
+	case outputCSV:
+		return writeCSVReport(o.path, results, start, end, opts)
+	case outputJSON:
+		data, err := json.MarshalIndent(newTemplateData(results, start, end, opts), "", "  ")
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(o.path, append(data, '\n'), 0o644)
+	case outputTemplate:
+		return writeTemplate(o.path, o.template, results, start, end, opts)
+	}
+
+	header, records, err := reportTable(results, opts)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(o.path)
+	if err != nil {
+		return err
+	}
+	switch o.format {
+	case outputMarkdown:
+		err = writeMarkdownReport(f, header, records, results, start, end, opts)
+	case outputHTML:
+		err = writeHTMLReport(f, header, records, results, start, end, opts)
+	case outputPrometheus:
+		err = writePrometheus(f, header, records)
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func writeMarkdownReport(f *os.File, header []string, records [][]string, results []repoResult, start, end time.Time, opts options) error {
+	fmt.Fprintf(f, "# %s\n\n", reportTitle(start, end, opts))
+	if err := writeMarkdown(f, header, records); err != nil {
+		return err
+	}
+	for _, sec := range reportSections(opts) {
+		fmt.Fprintf(f, "\n## %s\n\n", sec.name)
+		if err := writeMarkdown(f, sec.header, sec.records(results, start, end)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reportTitle is the heading of Markdown and HTML reports, which names the
+// range and its time zone.
+func reportTitle(start, end time.Time, opts options) string {
+	return fmt.Sprintf("GitHub contributor stats, %s to %s (%s)", start.Format("2006-01-02"), end.Format("2006-01-02"), opts.location)
+}
+
+var htmlReport = htmltemplate.Must(htmltemplate.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 0.2em 0.5em; }
+th { background: #7D56F4; color: #FAFAFA; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{range .Tables}}{{if .Name}}<h2>{{.Name}}</h2>
+{{end}}<table>
+<tr>{{range .Header}}<th>{{.}}</th>{{end}}</tr>
+{{range .Records}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>
+{{end}}</body>
+</html>
+`))
+
+type htmlTable struct {
+	Name    string
+	Header  []string
+	Records [][]string
+}
+
+func writeHTMLReport(f *os.File, header []string, records [][]string, results []repoResult, start, end time.Time, opts options) error {
+	tables := []htmlTable{{Header: header, Records: records}}
+	for _, sec := range reportSections(opts) {
+		tables = append(tables, htmlTable{sec.name, sec.header, sec.records(results, start, end)})
+	}
+	return htmlReport.Execute(f, struct {
+		Title  string
+		Tables []htmlTable
+	}{
+		Title:  reportTitle(start, end, opts),
+		Tables: tables,
+	})
+}
+
+// writePrometheus writes every metric column as a gauge, labelled with the
+// repository and the contributor or team of each row. Empty cells and
+// columns that are not numbers are left out.
+func writePrometheus(f *os.File, header []string, records [][]string) error {
+	var labels []int
+	for i, name := range header {
+		switch name {
+		case "Repository", "Contributor", "Team":
+			labels = append(labels, i)
+		}
+	}
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+	var sb strings.Builder
+	for i, name := range header {
+		if slices.Contains(keyColumns, name) || slices.Contains(ignoredColumns, name) {
+			continue
+		}
+		metric := "ghstats_" + snakeCase(name)
+		var samples []string
+		for _, record := range records {
+			if _, err := strconv.ParseFloat(record[i], 64); err != nil {
+				continue
+			}
+			var pairs []string
+			for _, l := range labels {
+				pairs = append(pairs, fmt.Sprintf(`%s="%s"`, strings.ToLower(header[l]), escape.Replace(record[l])))
+			}
+			samples = append(samples, fmt.Sprintf("%s{%s} %s\n", metric, strings.Join(pairs, ","), record[i]))
+		}
+		if len(samples) == 0 {
+			continue
+		}
+		fmt.Fprintf(&sb, "# HELP %s %s per row of the ghstats report.\n# TYPE %s gauge\n", metric, name, metric)
+		for _, s := range samples {
+			sb.WriteString(s)
+		}
+	}
+	_, err := f.WriteString(sb.String())
+	return err
+}
+
+// snakeCase converts a column name to a metric name, e.g. PRsMerged to
+// prs_merged and MedianPRSize to median_pr_size. Characters that are not
+// allowed in metric names become underscores.
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var sb strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			// An upper case letter starts a word after a lower case one,
+			// and ends an acronym when a lower case letter follows,
+			// unless that is the s of a plural such as PRs.
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			plural := nextLower && runes[i+1] == 's' && (i+2 == len(runes) || unicode.IsUpper(runes[i+2]))
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower && !plural) {
+				sb.WriteByte('_')
+			}
+		}
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			r = '_'
+		}
+		sb.WriteRune(unicode.ToLower(r))
+	}
+	return sb.String()
+}
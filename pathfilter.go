@@ -0,0 +1,122 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// defaultExcludedPaths are vendored, generated, lock and snapshot files that
+// are left out of line counts unless -default-excludes=false is given.
+var defaultExcludedPaths = []string{
+	"vendor/",
+	"node_modules/",
+	"*.pb.go",
+	"*.pb.gw.go",
+	"*_pb2.py",
+	"*_pb2_grpc.py",
+	"*.pb.cc",
+	"*.pb.h",
+	"zz_generated*",
+	"*.min.js",
+	"*.min.css",
+	"*.map",
+	"go.sum",
+	"package-lock.json",
+	"npm-shrinkwrap.json",
+	"yarn.lock",
+	"pnpm-lock.yaml",
+	"Cargo.lock",
+	"Gemfile.lock",
+	"poetry.lock",
+	"Pipfile.lock",
+	"composer.lock",
+	"Podfile.lock",
+	"mix.lock",
+	".terraform.lock.hcl",
+	"__snapshots__/",
+	"*.snap",
+}
+
+// pathFilter decides which files count towards line metrics when commits
+// are counted file by file.
+type pathFilter struct {
+	// include, when not empty, restricts counting to matching paths.
+	include []string
+	exclude []string
+	// attributes are the linguist rules of the repository's .gitattributes.
+	attributes []attributeRule
+}
+
+// attributeRule is a .gitattributes line setting or unsetting
+// linguist-generated or linguist-vendored.
+type attributeRule struct {
+	pattern   string
+	attribute string
+	set       bool
+}
+
+func (f pathFilter) empty() bool {
+	return len(f.include) == 0 && len(f.exclude) == 0 && len(f.attributes) == 0
+}
+
+// keep reports whether the lines changed in path count.
+func (f pathFilter) keep(path string) bool {
+	if len(f.include) > 0 && !matchAnyPath(f.include, path) {
+		return false
+	}
+	if matchAnyPath(f.exclude, path) {
+		return false
+	}
+	// Later lines of .gitattributes override earlier ones.
+	state := make(map[string]bool)
+	for _, rule := range f.attributes {
+		if matchPath(rule.pattern, path) {
+			state[rule.attribute] = rule.set
+		}
+	}
+	return !state["linguist-generated"] && !state["linguist-vendored"]
+}
+
+func matchAnyPath(patterns []string, path string) bool {
+	for _, pattern := range patterns {
+		if matchPath(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchGitAttributes reads the linguist-generated and linguist-vendored
+// rules of the .gitattributes file at the root of a repository's default
+// branch.
+func fetchGitAttributes(client *http.Client, owner, repo, token string) ([]attributeRule, error) {
+	data, found, err := fetchFileContent(client, owner, repo, ".gitattributes", token)
+	if err != nil || !found {
+		return nil, err
+	}
+	return parseGitAttributes(data), nil
+}
+
+func parseGitAttributes(data string) []attributeRule {
+	var rules []attributeRule
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		for _, attr := range fields[1:] {
+			name, value, hasValue := strings.Cut(attr, "=")
+			set := true
+			switch {
+			case strings.HasPrefix(name, "-") || strings.HasPrefix(name, "!"):
+				name, set = name[1:], false
+			case hasValue:
+				set = value != "false"
+			}
+			if name == "linguist-generated" || name == "linguist-vendored" {
+				rules = append(rules, attributeRule{pattern: fields[0], attribute: name, set: set})
+			}
+		}
+	}
+	return rules
+}
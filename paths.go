@@ -0,0 +1,88 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var (
+	pathPatternsMu sync.Mutex
+	pathPatterns   = make(map[string]*regexp.Regexp)
+)
+
+// matchPath reports whether a repository path matches a gitignore-style
+// pattern, as used by CODEOWNERS and .gitattributes:
+//
+//   - A pattern with a slash at the start or in the middle is relative to the
+//     repository root; otherwise it matches at any depth.
+//   - * and ? match within a path segment and ** matches across segments.
+//   - A pattern matching a directory matches everything inside it, except
+//     that a trailing /* only matches the files directly inside.
+func matchPath(pattern, path string) bool {
+	pathPatternsMu.Lock()
+	re, ok := pathPatterns[pattern]
+	if !ok {
+		re = compilePathPattern(pattern)
+		pathPatterns[pattern] = re
+	}
+	pathPatternsMu.Unlock()
+	return re.MatchString(path)
+}
+
+func compilePathPattern(pattern string) *regexp.Regexp {
+	anchored := strings.HasPrefix(pattern, "/") || strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	p := strings.TrimPrefix(pattern, "/")
+	dirOnly := strings.HasSuffix(p, "/")
+	p = strings.TrimSuffix(p, "/")
+
+	var sb strings.Builder
+	if anchored {
+		sb.WriteString("^")
+	} else {
+		sb.WriteString("^(?:.*/)?")
+	}
+	for i := 0; i < len(p); i++ {
+		switch {
+		case strings.HasPrefix(p[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(p[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case p[i] == '*':
+			sb.WriteString("[^/]*")
+		case p[i] == '?':
+			sb.WriteString("[^/]")
+		case p[i] == '[':
+			if end := strings.IndexByte(p[i:], ']'); end > 0 {
+				class := p[i+1 : i+end]
+				if strings.HasPrefix(class, "!") {
+					// Like every wildcard, a negated class never matches /.
+					class = "^/" + class[1:]
+				}
+				sb.WriteString("[" + class + "]")
+				i += end
+				continue
+			}
+			sb.WriteString(`\[`)
+		default:
+			sb.WriteString(regexp.QuoteMeta(p[i : i+1]))
+		}
+	}
+	switch {
+	case dirOnly:
+		sb.WriteString("/.*$")
+	case strings.HasSuffix(p, "/*"):
+		sb.WriteString("$")
+	default:
+		sb.WriteString("(?:/.*)?$")
+	}
+
+	re, err := regexp.Compile(sb.String())
+	if err != nil {
+		// Only a malformed character class can get here; match it literally.
+		return regexp.MustCompile("^" + regexp.QuoteMeta(pattern) + "$")
+	}
+	return re
+}
@@ -0,0 +1,72 @@
+package main
+
+import "testing"
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		// Without a slash, a pattern matches at any depth.
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/tool/main.go", true},
+		{"*.go", "main.go.orig", false},
+		{"vendor", "vendor/lib/a.go", true},
+		{"vendor", "src/vendor/lib/a.go", true},
+		{"vendor", "vendors/a.go", false},
+
+		// A leading or inner slash anchors the pattern at the root.
+		{"/build", "build/out.txt", true},
+		{"/build", "src/build/out.txt", false},
+		{"docs/api", "docs/api/index.md", true},
+		{"docs/api", "site/docs/api/index.md", false},
+
+		// A trailing slash only matches directories.
+		{"dist/", "dist/app.js", true},
+		{"dist/", "dist", false},
+		{"dist/", "src/dist/app.js", true},
+
+		// A trailing /* only matches the files directly inside.
+		{"docs/*", "docs/index.md", true},
+		{"docs/*", "docs/guide/intro.md", false},
+
+		// * and ? stay within a segment.
+		{"src/*.go", "src/main.go", true},
+		{"src/*.go", "src/pkg/main.go", false},
+		{"file?.txt", "file1.txt", true},
+		{"file?.txt", "file10.txt", false},
+		{"a?b", "a/b", false},
+
+		// ** matches across segments.
+		{"**/testdata", "testdata/x.json", true},
+		{"**/testdata", "pkg/a/testdata/x.json", true},
+		{"a/**/b", "a/b", true},
+		{"a/**/b", "a/x/y/b", true},
+		{"a/**/b", "a/x/c", false},
+		{"logs/**", "logs/2024/app.log", true},
+		{"logs/**", "logs", false},
+		{"logs/**", "old/logs/app.log", false},
+
+		// Character classes, negated with !.
+		{"file[0-9].txt", "file7.txt", true},
+		{"file[0-9].txt", "filex.txt", false},
+		{"file[!0-9].txt", "filex.txt", true},
+		{"file[!0-9].txt", "file7.txt", false},
+		{"a[!x]b", "a/b", false},
+
+		// Metacharacters of regular expressions are literal.
+		{"a+b.txt", "a+b.txt", true},
+		{"a+b.txt", "aab.txt", false},
+		{"(x).md", "(x).md", true},
+
+		// A malformed class is matched literally.
+		{"[abc", "[abc", true},
+		{"[abc", "a", false},
+	}
+	for _, tt := range tests {
+		if got := matchPath(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("matchPath(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
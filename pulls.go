@@ -0,0 +1,173 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+type PullRequest struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	User   struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	Labels []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	ClosedAt  *time.Time `json:"closed_at"`
+	MergedAt  *time.Time `json:"merged_at"`
+	Additions int        `json:"additions"`
+	Deletions int        `json:"deletions"`
+}
+
+// prMetrics holds a contributor's pull request activity. Sizes and merge
+// times are kept as samples so that medians can be computed after rows for
+// the same contributor have been combined.
+type prMetrics struct {
+	Opened         int
+	Merged         int
+	ClosedUnmerged int
+	Sizes          []int
+	MergeTimes     []time.Duration
+}
+
+// fetchPullRequests lists every pull request of a repository that was updated
+// on or after since, most recently updated first.
+func fetchPullRequests(client *http.Client, owner, repo, token string, since time.Time) ([]PullRequest, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls?state=all&sort=updated&direction=desc&per_page=100", githubAPI, owner, repo)
+	var prs []PullRequest
+	for url != "" {
+		var page []PullRequest
+		next, err := getJSON(client, url, token, &page)
+		if err != nil {
+			return nil, err
+		}
+		for _, pr := range page {
+			if pr.UpdatedAt.Before(since) {
+				return prs, nil
+			}
+			prs = append(prs, pr)
+		}
+		url = next
+	}
+	return prs, nil
+}
+
+// searchLimit is the number of results the search API returns at most.
+const searchLimit = 1000
+
+// errSearchLimit is returned by searchPullRequests when a search has more
+// results than the search API returns.
+var errSearchLimit = errors.New("more pull requests than the search API returns")
+
+// searchPullRequests finds the pull requests of a repository created or
+// closed within the range, or only those closed with closedOnly. Merged
+// pull requests are closed when they are merged. Unlike listing every pull
+// request updated since the start, this costs a request per 100 pull
+// requests of the range, however long ago it was.
+func searchPullRequests(client *http.Client, owner, repo, token string, start, end time.Time, closedOnly bool) ([]PullRequest, error) {
+	qualifiers := []string{"created", "closed"}
+	if closedOnly {
+		qualifiers = qualifiers[1:]
+	}
+	var prs []PullRequest
+	seen := make(map[int]bool)
+	for _, qualifier := range qualifiers {
+		query := fmt.Sprintf("repo:%s/%s is:pr %s:%s..%s", owner, repo, qualifier,
+			start.UTC().Format(time.RFC3339), end.UTC().Format(time.RFC3339))
+		next := fmt.Sprintf("%s/search/issues?per_page=100&q=%s", githubAPI, url.QueryEscape(query))
+		for next != "" {
+			// Search results have the merge time in pull_request.
+			var page struct {
+				TotalCount int `json:"total_count"`
+				Items      []struct {
+					PullRequest
+					PR struct {
+						MergedAt *time.Time `json:"merged_at"`
+					} `json:"pull_request"`
+				} `json:"items"`
+			}
+			var err error
+			if next, err = getJSON(client, next, token, &page); err != nil {
+				return nil, err
+			}
+			if page.TotalCount > searchLimit {
+				return nil, errSearchLimit
+			}
+			for _, item := range page.Items {
+				if seen[item.Number] {
+					continue
+				}
+				seen[item.Number] = true
+				pr := item.PullRequest
+				pr.MergedAt = item.PR.MergedAt
+				prs = append(prs, pr)
+			}
+		}
+	}
+	return prs, nil
+}
+
+// fetchPullRequestSizes fills in additions and deletions, which the list
+// endpoint omits, for the pull requests opened within the range.
+func fetchPullRequestSizes(client *http.Client, owner, repo, token string, prs []PullRequest, start, end time.Time) error {
+	for i := range prs {
+		if !inRange(prs[i].CreatedAt, start, end) {
+			continue
+		}
+		url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d", githubAPI, owner, repo, prs[i].Number)
+		if _, err := getJSON(client, url, token, &prs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func processPullRequests(prs []PullRequest, start, end time.Time) map[string]*prMetrics {
+	metrics := make(map[string]*prMetrics)
+	get := func(login string) *prMetrics {
+		if metrics[login] == nil {
+			metrics[login] = &prMetrics{}
+		}
+		return metrics[login]
+	}
+
+	for _, pr := range prs {
+		login := pr.User.Login
+		if inRange(pr.CreatedAt, start, end) {
+			m := get(login)
+			m.Opened++
+			m.Sizes = append(m.Sizes, pr.Additions+pr.Deletions)
+		}
+		if pr.MergedAt != nil && inRange(*pr.MergedAt, start, end) {
+			m := get(login)
+			m.Merged++
+			m.MergeTimes = append(m.MergeTimes, pr.MergedAt.Sub(pr.CreatedAt))
+		}
+		if pr.MergedAt == nil && pr.ClosedAt != nil && inRange(*pr.ClosedAt, start, end) {
+			get(login).ClosedUnmerged++
+		}
+	}
+	return metrics
+}
+
+// joinPullRequests attaches pull request metrics to the contributor rows of a
+// repository by login.
+func joinPullRequests(rows *repoRows, metrics map[string]*prMetrics) {
+	for _, login := range sortedKeys(metrics) {
+		rows.get(login).PRs = *metrics[login]
+	}
+}
+
+func (m *prMetrics) add(other prMetrics) {
+	m.Opened += other.Opened
+	m.Merged += other.Merged
+	m.ClosedUnmerged += other.ClosedUnmerged
+	m.Sizes = append(m.Sizes, other.Sizes...)
+	m.MergeTimes = append(m.MergeTimes, other.MergeTimes...)
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// rowQuery computes columns, filters, sorts and limits the rows of the
+// report before they are written, as given by -column, -where, -sort and
+// -limit.
+type rowQuery struct {
+	columns []computedColumn
+	where   []expr
+	sort    []sortKey
+	limit   int
+}
+
+type computedColumn struct {
+	name string
+	expr expr
+}
+
+type sortKey struct {
+	column string
+	desc   bool
+}
+
+// parseColumn parses a -column definition, e.g. "net = additions - deletions".
+func parseColumn(definition string) (computedColumn, error) {
+	name, source, ok := strings.Cut(definition, "=")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" || strings.ContainsAny(name, " \t") {
+		return computedColumn{}, fmt.Errorf("invalid -column %q: want name = expression", definition)
+	}
+	e, err := parseExpr(source)
+	if err != nil {
+		return computedColumn{}, fmt.Errorf("invalid -column %q: %w", definition, err)
+	}
+	return computedColumn{name, e}, nil
+}
+
+// parseCondition parses a -where expression, which must be a condition.
+func parseCondition(source string) (expr, error) {
+	e, err := parseExpr(source)
+	if err == nil && !e.condition {
+		err = fmt.Errorf("want a condition, e.g. commits >= 5")
+	}
+	if err != nil {
+		return expr{}, fmt.Errorf("invalid -where %q: %w", source, err)
+	}
+	return e, nil
+}
+
+// parseSort parses a comma-separated list of columns to sort by. A column
+// prefixed with - is sorted in descending order.
+func parseSort(list string) []sortKey {
+	var keys []sortKey
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		desc := strings.HasPrefix(name, "-")
+		keys = append(keys, sortKey{normalizeColumn(strings.TrimPrefix(name, "-")), desc})
+	}
+	return keys
+}
+
+func (q rowQuery) empty() bool {
+	return len(q.columns) == 0 && len(q.where) == 0 && len(q.sort) == 0 && q.limit == 0
+}
+
+// check reports columns that the query refers to but the header lacks, and
+// computed columns whose names the header already has.
+func (q rowQuery) check(header []string) error {
+	known := make(map[string]bool)
+	for _, name := range header {
+		known[normalizeColumn(name)] = true
+	}
+	unknown := func(name string) error {
+		if !known[name] {
+			return fmt.Errorf("unknown column %q", name)
+		}
+		return nil
+	}
+	for _, c := range q.columns {
+		for _, name := range c.expr.idents {
+			if err := unknown(name); err != nil {
+				return fmt.Errorf("-column %s: %w", c.name, err)
+			}
+		}
+		if known[normalizeColumn(c.name)] {
+			return fmt.Errorf("-column %s: the report already has a column of that name", c.name)
+		}
+		known[normalizeColumn(c.name)] = true
+	}
+	for _, w := range q.where {
+		for _, name := range w.idents {
+			if err := unknown(name); err != nil {
+				return fmt.Errorf("-where %s: %w", w.source, err)
+			}
+		}
+	}
+	for _, k := range q.sort {
+		if err := unknown(k.column); err != nil {
+			return fmt.Errorf("-sort: %w", err)
+		}
+	}
+	return nil
+}
+
+// apply runs the query on rendered rows: computed columns are appended in
+// order, so that each can use the ones before it, then the rows are
+// filtered, sorted and limited.
+func (q rowQuery) apply(header []string, records [][]string) ([]string, [][]string, error) {
+	index := make(map[string]int)
+	for i, name := range header {
+		index[normalizeColumn(name)] = i
+	}
+	rowOf := func(record []string) func(string) string {
+		return func(name string) string {
+			if i, ok := index[name]; ok && i < len(record) {
+				return record[i]
+			}
+			return ""
+		}
+	}
+
+	header = slices.Clone(header)
+	records = slices.Clone(records)
+	for i := range records {
+		records[i] = slices.Clone(records[i])
+	}
+	for _, c := range q.columns {
+		for i, record := range records {
+			v, err := c.expr.eval(rowOf(record))
+			if err != nil {
+				return nil, nil, fmt.Errorf("-column %s: %w", c.name, err)
+			}
+			records[i] = append(record, formatValue(v))
+		}
+		index[normalizeColumn(c.name)] = len(header)
+		header = append(header, c.name)
+	}
+
+	var kept [][]string
+	for _, record := range records {
+		keep := true
+		for _, w := range q.where {
+			b, err := evalBool(w.eval, rowOf(record), "-where")
+			if err != nil {
+				return nil, nil, fmt.Errorf("-where %s: %w", w.source, err)
+			}
+			keep = keep && b
+		}
+		if keep {
+			kept = append(kept, record)
+		}
+	}
+
+	if len(q.sort) > 0 {
+		slices.SortStableFunc(kept, func(a, b []string) int {
+			for _, k := range q.sort {
+				x, y := a[index[k.column]], b[index[k.column]]
+				// Empty cells go last in either order.
+				if (x == "") != (y == "") {
+					if x == "" {
+						return 1
+					}
+					return -1
+				}
+				var c int
+				xn, xErr := strconv.ParseFloat(x, 64)
+				yn, yErr := strconv.ParseFloat(y, 64)
+				if xErr == nil && yErr == nil {
+					c = compareFloats(xn, yn)
+				} else {
+					c = strings.Compare(x, y)
+				}
+				if k.desc {
+					c = -c
+				}
+				if c != 0 {
+					return c
+				}
+			}
+			return 0
+		})
+	}
+
+	if q.limit > 0 && len(kept) > q.limit {
+		kept = kept[:q.limit]
+	}
+	return header, kept, nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func testQuery(t *testing.T, columns []string, where []string, sort string, limit int) rowQuery {
+	t.Helper()
+	q := rowQuery{sort: parseSort(sort), limit: limit}
+	for _, definition := range columns {
+		c, err := parseColumn(definition)
+		if err != nil {
+			t.Fatal(err)
+		}
+		q.columns = append(q.columns, c)
+	}
+	for _, source := range where {
+		e, err := parseCondition(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		q.where = append(q.where, e)
+	}
+	return q
+}
+
+func TestParseColumn(t *testing.T) {
+	for _, definition := range []string{
+		"additions - deletions",
+		"= additions",
+		"net lines = additions",
+		"net = additions -",
+		"next = repo + 1",
+		"active = commits && true",
+	} {
+		if _, err := parseColumn(definition); err == nil {
+			t.Errorf("parseColumn(%q) succeeded, want an error", definition)
+		}
+	}
+}
+
+func TestRowQueryCheck(t *testing.T) {
+	header := []string{"Repository", "Contributor", "Additions", "Deletions"}
+	tests := []struct {
+		columns []string
+		where   []string
+		sort    string
+		ok      bool
+	}{
+		{[]string{"net = additions - deletions"}, []string{"net > 0"}, "-net,login", true},
+		{[]string{"net = additions - deletions", "ratio = net / additions"}, nil, "", true},
+		{[]string{"net = commits"}, nil, "", false},
+		{nil, []string{"commits > 0"}, "", false},
+		{nil, nil, "commits", false},
+		// A computed column may not shadow one of the report.
+		{[]string{"Additions = deletions"}, nil, "", false},
+		{[]string{"repo = contributor"}, nil, "", false},
+		{[]string{"net = additions", "Net = deletions"}, nil, "", false},
+	}
+	for _, tt := range tests {
+		q := testQuery(t, tt.columns, tt.where, tt.sort, 0)
+		if err := q.check(header); (err == nil) != tt.ok {
+			t.Errorf("check(%q, %q, %q) = %v, want ok %v", tt.columns, tt.where, tt.sort, err, tt.ok)
+		}
+	}
+}
+
+func TestRowQueryApply(t *testing.T) {
+	header := []string{"Contributor", "Additions", "Deletions"}
+	records := [][]string{
+		{"alice", "10", "4"},
+		{"bob", "3", "5"},
+		{"carol", "", "1"},
+		{"dave", "7", "1"},
+	}
+	q := testQuery(t, []string{"net = additions - deletions"}, []string{`login != "dave"`}, "-net", 2)
+	gotHeader, got, err := q.apply(header, records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Contributor", "Additions", "Deletions", "net"}; !slices.Equal(gotHeader, want) {
+		t.Errorf("header = %q, want %q", gotHeader, want)
+	}
+	want := [][]string{{"alice", "10", "4", "6"}, {"bob", "3", "5", "-2"}}
+	if !slices.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("records = %q, want %q", got, want)
+	}
+	if records[0][len(records[0])-1] != "4" {
+		t.Errorf("apply changed its input: %q", records[0])
+	}
+
+	// Empty cells sort last in either order.
+	for _, sort := range []string{"additions", "-additions"} {
+		_, got, err := testQuery(t, nil, nil, sort, 0).apply(header, records)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got[len(got)-1][0] != "carol" {
+			t.Errorf("-sort %s: last row is %q, want carol", sort, got[len(got)-1][0])
+		}
+	}
+}
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"cmp"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// unlinkedContributor stands in for the login of commit authors whose email
+// is not linked to a GitHub account.
+const unlinkedContributor = "(unlinked)"
+
+// contributorRow is one line of the contributor report: a contributor's
+// activity in a single repository over the selected range.
+type contributorRow struct {
+	Repository  string
+	Contributor string
+	Name        string
+	Team        string
+	Logins      []string
+	Emails      []string
+	Bot         bool
+	Additions   int
+	Deletions   int
+	Commits     int
+	// ExcludedCommits and ExcludedLines tally the bulk-change commits left
+	// out of the totals above.
+	ExcludedCommits int
+	ExcludedLines   int
+	Start           time.Time
+	End             time.Time
+	PRs             prMetrics
+	Reviews         reviewMetrics
+	Issues          issueMetrics
+	Owners          lineBreakdown
+	Languages       lineBreakdown
+	Weeks           weeklyActivity
+	// RepoCommits is the total number of commits in the repository, or in
+	// all repositories for overall team rows, including those of bots.
+	RepoCommits int
+}
+
+type lineCounts struct {
+	Additions int
+	Deletions int
+}
+
+// lineBreakdown splits the lines a contributor changed by a property of the
+// files changed, such as their owners.
+type lineBreakdown map[string]lineCounts
+
+// add returns b with c added to key, allocating b if needed.
+func (b lineBreakdown) add(key string, c lineCounts) lineBreakdown {
+	if b == nil {
+		b = make(lineBreakdown)
+	}
+	total := b[key]
+	total.Additions += c.Additions
+	total.Deletions += c.Deletions
+	b[key] = total
+	return b
+}
+
+func (b lineBreakdown) merge(other lineBreakdown) lineBreakdown {
+	for key, c := range other {
+		b = b.add(key, c)
+	}
+	return b
+}
+
+// add combines the activity of other into r. Both are expected to be of the
+// same repository, so RepoCommits is kept.
+func (r *contributorRow) add(other contributorRow) {
+	r.Additions += other.Additions
+	r.Deletions += other.Deletions
+	r.Commits += other.Commits
+	r.ExcludedCommits += other.ExcludedCommits
+	r.ExcludedLines += other.ExcludedLines
+	r.Logins = append(r.Logins, other.Logins...)
+	r.Emails = append(r.Emails, other.Emails...)
+	r.Bot = r.Bot || other.Bot
+	r.PRs.add(other.PRs)
+	r.Reviews.add(other.Reviews)
+	r.Issues.add(other.Issues)
+	r.Owners = r.Owners.merge(other.Owners)
+	r.Languages = r.Languages.merge(other.Languages)
+	r.Weeks = r.Weeks.merge(other.Weeks)
+}
+
+// repoRows indexes the contributor rows of one repository by login so that
+// metrics from different endpoints can be joined onto them. Contributors
+// seen for the first time get a new row, appended after the existing ones.
+type repoRows struct {
+	repo    string
+	order   []string
+	byLogin map[string]*contributorRow
+}
+
+func newRepoRows(repo string, rows []contributorRow) *repoRows {
+	rr := &repoRows{repo: repo, byLogin: make(map[string]*contributorRow)}
+	for i := range rows {
+		rr.order = append(rr.order, rows[i].Contributor)
+		rr.byLogin[rows[i].Contributor] = &rows[i]
+	}
+	return rr
+}
+
+func (rr *repoRows) get(login string) *contributorRow {
+	if r, ok := rr.byLogin[login]; ok {
+		return r
+	}
+	r := &contributorRow{Repository: rr.repo, Contributor: login}
+	rr.order = append(rr.order, login)
+	rr.byLogin[login] = r
+	return r
+}
+
+func (rr *repoRows) rows() []contributorRow {
+	rows := make([]contributorRow, len(rr.order))
+	for i, login := range rr.order {
+		rows[i] = *rr.byLogin[login]
+	}
+	return rows
+}
+
+// repoResult is everything collected for one repository.
+type repoResult struct {
+	Repository string
+	Rows       []contributorRow
+	Bots       []contributorRow
+	Issues     issueMetrics
+	DORA       doraMetrics
+	Stats      repoStats
+	// Commits is the total number of commits in the repository, including
+	// those of bots.
+	Commits int
+	// Baseline holds the same for the baseline period with -compare.
+	Baseline *repoResult
+}
+
+type column struct {
+	name  string
+	value func(r contributorRow) string
+}
+
+var baseColumns = []column{
+	{"Repository", func(r contributorRow) string { return r.Repository }},
+	{"Contributor", func(r contributorRow) string { return r.Contributor }},
+	{"Additions", func(r contributorRow) string { return strconv.Itoa(r.Additions) }},
+	{"Deletions", func(r contributorRow) string { return strconv.Itoa(r.Deletions) }},
+	{"Commits", func(r contributorRow) string { return strconv.Itoa(r.Commits) }},
+	{"StartDate", func(r contributorRow) string { return r.Start.Format("2006-01-02") }},
+	{"EndDate", func(r contributorRow) string { return r.End.Format("2006-01-02") }},
+}
+
+// pullColumns are written with -prs.
+var pullColumns = []column{
+	{"PRsOpened", func(r contributorRow) string { return strconv.Itoa(r.PRs.Opened) }},
+	{"PRsMerged", func(r contributorRow) string { return strconv.Itoa(r.PRs.Merged) }},
+	{"PRsClosedUnmerged", func(r contributorRow) string { return strconv.Itoa(r.PRs.ClosedUnmerged) }},
+	{"MedianPRSize", func(r contributorRow) string { return formatMedian(medianInt(r.PRs.Sizes)) }},
+	{"MedianHoursToMerge", func(r contributorRow) string { return formatHours(medianDuration(r.PRs.MergeTimes)) }},
+}
+
+// reviewColumns are written with -reviews.
+var reviewColumns = []column{
+	{"ReviewsApproved", func(r contributorRow) string { return strconv.Itoa(r.Reviews.Approvals) }},
+	{"ReviewsChangesRequested", func(r contributorRow) string { return strconv.Itoa(r.Reviews.ChangesRequested) }},
+	{"ReviewsCommented", func(r contributorRow) string { return strconv.Itoa(r.Reviews.Comments) }},
+	{"ReviewComments", func(r contributorRow) string { return strconv.Itoa(r.Reviews.ReviewComments) }},
+	{"PRsReviewed", func(r contributorRow) string { return strconv.Itoa(r.Reviews.PRsReviewed) }},
+	{"MedianHoursToFirstReview", func(r contributorRow) string { return formatHours(medianDuration(r.Reviews.FirstReviewTimes)) }},
+}
+
+var identityColumns = []column{
+	{"Name", func(r contributorRow) string { return r.Name }},
+	{"Team", func(r contributorRow) string { return r.Team }},
+	{"Logins", func(r contributorRow) string { return strings.Join(r.Logins, " ") }},
+}
+
+var excludedColumns = []column{
+	{"ExcludedCommits", func(r contributorRow) string { return strconv.Itoa(r.ExcludedCommits) }},
+	{"ExcludedLines", func(r contributorRow) string { return strconv.Itoa(r.ExcludedLines) }},
+}
+
+var issueColumns = []column{
+	{"IssuesOpened", func(r contributorRow) string { return strconv.Itoa(r.Issues.Opened) }},
+	{"IssuesClosed", func(r contributorRow) string { return strconv.Itoa(r.Issues.Closed) }},
+	{"IssuesCommented", func(r contributorRow) string { return strconv.Itoa(r.Issues.Commented) }},
+	{"MedianHoursToClose", func(r contributorRow) string { return formatHours(medianDuration(r.Issues.CloseTimes)) }},
+}
+
+// metricColumns returns the columns that hold metrics, leaving out those
+// naming the row and its range.
+func metricColumns(columns []column) []column {
+	var metrics []column
+	for _, c := range columns {
+		switch c.name {
+		case "Repository", "Contributor", "Name", "Team", "Logins", "Members", "StartDate", "EndDate", "TimeZone":
+		default:
+			metrics = append(metrics, c)
+		}
+	}
+	return metrics
+}
+
+// contributorColumns returns the columns of the contributor report for the
+// metric sets enabled in opts.
+func contributorColumns(opts options) []column {
+	columns := slices.Clone(baseColumns)
+	if opts.prs {
+		columns = append(columns, pullColumns...)
+	}
+	if opts.reviews {
+		columns = append(columns, reviewColumns...)
+	}
+	columns = append(columns, derivedColumns...)
+	// The dates are labelled with the time zone they are in.
+	i := slices.IndexFunc(columns, func(c column) bool { return c.name == "EndDate" })
+	columns = slices.Insert(columns, i+1, column{"TimeZone", func(contributorRow) string { return opts.location.String() }})
+	if opts.identities != nil {
+		columns = slices.Insert(columns, 2, identityColumns...)
+	}
+	if opts.commitIgnore.active() {
+		i := slices.IndexFunc(columns, func(c column) bool { return c.name == "Commits" })
+		columns = slices.Insert(columns, i+1, excludedColumns...)
+	}
+	if opts.issues {
+		columns = append(columns, issueColumns...)
+	}
+	return columns
+}
+
+// teamColumns returns the columns of team rows: those of the contributor
+// report, with the team and its members in place of the contributor. With
+// -teams-only the members are left out, so that no individual is named.
+func teamColumns(opts options) []column {
+	columns := []column{
+		{"Repository", func(r contributorRow) string { return r.Repository }},
+		{"Team", func(r contributorRow) string { return r.Team }},
+	}
+	if !opts.teamsOnly {
+		columns = append(columns, column{"Members", func(r contributorRow) string { return strings.Join(r.Logins, " ") }})
+	}
+	for _, c := range contributorColumns(opts) {
+		switch c.name {
+		case "Repository", "Contributor", "Name", "Team", "Logins":
+		default:
+			columns = append(columns, c)
+		}
+	}
+	return columns
+}
+
+// writeReport writes the report in every requested output, see
+// writeOutput.
+func writeReport(outputs []output, results []repoResult, start, end time.Time, opts options) error {
+	for _, o := range outputs {
+		if err := writeOutput(o, results, start, end, opts); err != nil {
+			return fmt.Errorf("writing %s: %w", o.path, err)
+		}
+	}
+	return nil
+}
+
+// reportTable renders the rows of the report: the contributor rows, or only
+// the team rows with -teams-only. The query of -column, -where, -sort and
+// -limit applies to them, but not to the sections.
+func reportTable(results []repoResult, opts options) ([]string, [][]string, error) {
+	var header []string
+	var records [][]string
+	if opts.teamsOnly {
+		header, records = renderRows(teamColumns(opts), teamRows(results, opts.teams))
+	} else {
+		var rows []contributorRow
+		for _, result := range results {
+			rows = append(rows, result.Rows...)
+		}
+		header, records = renderRows(contributorColumns(opts), rows)
+	}
+	if opts.query.empty() {
+		return header, records, nil
+	}
+	return opts.query.apply(header, records)
+}
+
+// writeCSVReport writes the rows of the report to out and every enabled
+// section to a file next to it, see sectionPath.
+func writeCSVReport(out string, results []repoResult, start, end time.Time, opts options) error {
+	header, records, err := reportTable(results, opts)
+	if err != nil {
+		return err
+	}
+	if err := writeCSV(out, header, records); err != nil {
+		return err
+	}
+	for _, sec := range reportSections(opts) {
+		if err := writeCSV(sectionPath(out, sec.name), sec.header, sec.records(results, start, end)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func renderRows(columns []column, rows []contributorRow) ([]string, [][]string) {
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = c.name
+	}
+	var records [][]string
+	for _, r := range rows {
+		record := make([]string, len(columns))
+		for i, c := range columns {
+			record[i] = c.value(r)
+		}
+		records = append(records, record)
+	}
+	return header, records
+}
+
+// sectionPath returns the file a report section is written to: the output
+// path with the section name inserted before the extension, so that the
+// "issues" section of output.csv goes to output.issues.csv.
+func sectionPath(out, section string) string {
+	ext := filepath.Ext(out)
+	return strings.TrimSuffix(out, ext) + "." + section + ext
+}
+
+func writeCSV(path string, header []string, records [][]string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	writer := csv.NewWriter(f)
+	writer.Write(header)
+	if err := writer.WriteAll(records); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func inRange(t, start, end time.Time) bool {
+	return !t.Before(start) && !t.After(end)
+}
+
+// medianInt returns the median of the samples, or -1 when there are none.
+func medianInt(samples []int) float64 {
+	if len(samples) == 0 {
+		return -1
+	}
+	sorted := slices.Clone(samples)
+	slices.Sort(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return float64(sorted[mid-1]+sorted[mid]) / 2
+	}
+	return float64(sorted[mid])
+}
+
+// medianDuration returns the median of the samples, or -1 when there are none.
+func medianDuration(samples []time.Duration) time.Duration {
+	if len(samples) == 0 {
+		return -1
+	}
+	sorted := slices.Clone(samples)
+	slices.Sort(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// formatMedian renders a median for the CSV, leaving the cell empty when
+// there was nothing to take the median of.
+func formatMedian(v float64) string {
+	if v < 0 {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func formatHours(d time.Duration) string {
+	if d < 0 {
+		return ""
+	}
+	return fmt.Sprintf("%.1f", d.Hours())
+}
+
+func sortedKeys[K cmp.Ordered, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type CommitActivity struct {
+	Days  [7]int `json:"days"`
+	Total int    `json:"total"`
+	Week  int64  `json:"week"`
+}
+
+type Participation struct {
+	All   []int `json:"all"`
+	Owner []int `json:"owner"`
+}
+
+// repoStats holds the repository-level statistics that fall within the
+// range. The punch card cannot be limited to the range and covers the whole
+// history of the repository.
+type repoStats struct {
+	Weeks         []repoWeek
+	Days          []repoDay
+	Participation []participationWeek
+	PunchCard     []punchCardHour
+}
+
+// repoWeek is a week of code frequency. Commit counts are only known for the
+// last 52 weeks and are -1 before that.
+type repoWeek struct {
+	Week      time.Time
+	Additions int
+	Deletions int
+	Commits   int
+}
+
+type repoDay struct {
+	Date    time.Time
+	Commits int
+}
+
+type participationWeek struct {
+	Week  time.Time
+	Owner int
+	All   int
+}
+
+type punchCardHour struct {
+	Day     time.Weekday
+	Hour    int
+	Commits int
+}
+
+// fetchRepoStats fetches the code frequency, commit activity, participation
+// and punch card statistics of a repository.
+func fetchRepoStats(client *http.Client, owner, repo, token string) ([][3]int64, []CommitActivity, Participation, [][3]int, error) {
+	var (
+		frequency     [][3]int64
+		activity      []CommitActivity
+		participation Participation
+		punchCard     [][3]int
+	)
+	base := fmt.Sprintf("%s/repos/%s/%s/stats", githubAPI, owner, repo)
+	if err := fetchStats(client, base+"/code_frequency", token, &frequency); err != nil {
+		return nil, nil, Participation{}, nil, err
+	}
+	if err := fetchStats(client, base+"/commit_activity", token, &activity); err != nil {
+		return nil, nil, Participation{}, nil, err
+	}
+	if err := fetchStats(client, base+"/participation", token, &participation); err != nil {
+		return nil, nil, Participation{}, nil, err
+	}
+	if err := fetchStats(client, base+"/punch_card", token, &punchCard); err != nil {
+		return nil, nil, Participation{}, nil, err
+	}
+	return frequency, activity, participation, punchCard, nil
+}
+
+// processRepoStats keeps the weeks and days of the statistics that overlap
+// the range. Participation is reported for the last 52 weeks, ending with
+// the current one, and is dated relative to now.
+func processRepoStats(frequency [][3]int64, activity []CommitActivity, participation Participation, punchCard [][3]int, start, end, now time.Time) repoStats {
+	var stats repoStats
+	overlaps := func(week time.Time) bool {
+		return !week.After(end) && !week.Add(7*24*time.Hour).Before(start)
+	}
+
+	commits := make(map[int64]int)
+	for _, a := range activity {
+		commits[a.Week] = a.Total
+		week := time.Unix(a.Week, 0).UTC()
+		for i, n := range a.Days {
+			day := week.AddDate(0, 0, i)
+			if !day.After(end) && day.AddDate(0, 0, 1).After(start) {
+				stats.Days = append(stats.Days, repoDay{Date: day, Commits: n})
+			}
+		}
+	}
+	for _, f := range frequency {
+		week := time.Unix(f[0], 0).UTC()
+		if !overlaps(week) {
+			continue
+		}
+		total, ok := commits[f[0]]
+		if !ok {
+			total = -1
+		}
+		stats.Weeks = append(stats.Weeks, repoWeek{
+			Week:      week,
+			Additions: int(f[1]),
+			Deletions: int(-f[2]),
+			Commits:   total,
+		})
+	}
+
+	now = now.UTC()
+	currentWeek := time.Date(now.Year(), now.Month(), now.Day()-int(now.Weekday()), 0, 0, 0, 0, time.UTC)
+	for i := range participation.All {
+		week := currentWeek.AddDate(0, 0, -7*(len(participation.All)-1-i))
+		if !overlaps(week) {
+			continue
+		}
+		pw := participationWeek{Week: week, All: participation.All[i]}
+		if i < len(participation.Owner) {
+			pw.Owner = participation.Owner[i]
+		}
+		stats.Participation = append(stats.Participation, pw)
+	}
+
+	for _, p := range punchCard {
+		stats.PunchCard = append(stats.PunchCard, punchCardHour{Day: time.Weekday(p[0]), Hour: p[1], Commits: p[2]})
+	}
+	return stats
+}
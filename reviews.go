@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Review struct {
+	User struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	State       string     `json:"state"`
+	SubmittedAt *time.Time `json:"submitted_at"`
+}
+
+type ReviewComment struct {
+	User struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	CreatedAt      time.Time `json:"created_at"`
+	PullRequestURL string    `json:"pull_request_url"`
+}
+
+// reviewMetrics holds the code review activity of a reviewer. Reviews on
+// their own pull requests are not counted.
+type reviewMetrics struct {
+	Approvals        int
+	ChangesRequested int
+	Comments         int
+	ReviewComments   int
+	PRsReviewed      int
+	FirstReviewTimes []time.Duration
+}
+
+// fetchReviews fetches the reviews of each pull request, keyed by number.
+func fetchReviews(client *http.Client, owner, repo, token string, prs []PullRequest) (map[int][]Review, error) {
+	reviews := make(map[int][]Review)
+	for _, pr := range prs {
+		url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d/reviews?per_page=100", githubAPI, owner, repo, pr.Number)
+		for url != "" {
+			var page []Review
+			next, err := getJSON(client, url, token, &page)
+			if err != nil {
+				return nil, err
+			}
+			reviews[pr.Number] = append(reviews[pr.Number], page...)
+			url = next
+		}
+	}
+	return reviews, nil
+}
+
+// fetchReviewComments lists the review comments of a repository that were
+// updated on or after since.
+func fetchReviewComments(client *http.Client, owner, repo, token string, since time.Time) ([]ReviewComment, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls/comments?sort=created&direction=desc&per_page=100&since=%s",
+		githubAPI, owner, repo, since.UTC().Format(time.RFC3339))
+	var comments []ReviewComment
+	for url != "" {
+		var page []ReviewComment
+		next, err := getJSON(client, url, token, &page)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, page...)
+		url = next
+	}
+	return comments, nil
+}
+
+func processReviews(prs []PullRequest, reviews map[int][]Review, comments []ReviewComment, start, end time.Time) map[string]*reviewMetrics {
+	metrics := make(map[string]*reviewMetrics)
+	get := func(login string) *reviewMetrics {
+		if metrics[login] == nil {
+			metrics[login] = &reviewMetrics{}
+		}
+		return metrics[login]
+	}
+
+	authors := make(map[int]string)
+	for _, pr := range prs {
+		authors[pr.Number] = pr.User.Login
+
+		var first *Review
+		reviewed := make(map[string]bool)
+		for i, review := range reviews[pr.Number] {
+			login := review.User.Login
+			if review.SubmittedAt == nil || login == pr.User.Login {
+				continue
+			}
+			if first == nil || review.SubmittedAt.Before(*first.SubmittedAt) {
+				first = &reviews[pr.Number][i]
+			}
+			if !inRange(*review.SubmittedAt, start, end) {
+				continue
+			}
+			switch review.State {
+			case "APPROVED":
+				get(login).Approvals++
+			case "CHANGES_REQUESTED":
+				get(login).ChangesRequested++
+			case "COMMENTED":
+				get(login).Comments++
+			default:
+				continue
+			}
+			if !reviewed[login] {
+				reviewed[login] = true
+				get(login).PRsReviewed++
+			}
+		}
+		if first != nil && inRange(*first.SubmittedAt, start, end) {
+			m := get(first.User.Login)
+			m.FirstReviewTimes = append(m.FirstReviewTimes, first.SubmittedAt.Sub(pr.CreatedAt))
+		}
+	}
+
+	for _, comment := range comments {
+		if !inRange(comment.CreatedAt, start, end) {
+			continue
+		}
+		number, _ := strconv.Atoi(comment.PullRequestURL[strings.LastIndex(comment.PullRequestURL, "/")+1:])
+		if authors[number] == comment.User.Login {
+			continue
+		}
+		get(comment.User.Login).ReviewComments++
+	}
+	return metrics
+}
+
+// joinReviews attaches review metrics to the contributor rows of a
+// repository by login.
+func joinReviews(rows *repoRows, metrics map[string]*reviewMetrics) {
+	for _, login := range sortedKeys(metrics) {
+		rows.get(login).Reviews = *metrics[login]
+	}
+}
+
+func (m *reviewMetrics) add(other reviewMetrics) {
+	m.Approvals += other.Approvals
+	m.ChangesRequested += other.ChangesRequested
+	m.Comments += other.Comments
+	m.ReviewComments += other.ReviewComments
+	m.PRsReviewed += other.PRsReviewed
+	m.FirstReviewTimes = append(m.FirstReviewTimes, other.FirstReviewTimes...)
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"strconv"
+	"time"
+)
+
+// section is a repository-level part of the report, written to its own file
+// next to the contributor rows.
+type section struct {
+	name    string
+	header  []string
+	records func(results []repoResult, start, end time.Time) [][]string
+}
+
+// reportSections returns the sections enabled in opts.
+func reportSections(opts options) []section {
+	var sections []section
+	if opts.bots == botsSeparate && !opts.teamsOnly {
+		sections = append(sections, botSection(opts))
+	}
+	if opts.teamRollup() && !opts.teamsOnly {
+		sections = append(sections, teamSection(opts))
+	}
+	if opts.codeOwners {
+		sections = append(sections, breakdownSection("owners", "Owner", func(r contributorRow) lineBreakdown { return r.Owners }, opts))
+	}
+	if opts.languages {
+		sections = append(sections, breakdownSection("languages", "Language", func(r contributorRow) lineBreakdown { return r.Languages }, opts))
+	}
+	if opts.compare {
+		sections = append(sections, comparisonSection(opts))
+	}
+	if opts.issues {
+		sections = append(sections, issueSection)
+	}
+	if opts.dora {
+		sections = append(sections, doraSection)
+	}
+	if opts.repoStats {
+		sections = append(sections, weeklySection, dailySection, participationSection, punchCardSection)
+	}
+	return sections
+}
+
+// botSection lists the rows of bots and automation accounts, with the same
+// columns as the contributor rows.
+func botSection(opts options) section {
+	columns := contributorColumns(opts)
+	header, _ := renderRows(columns, nil)
+	return section{
+		name:   "bots",
+		header: header,
+		records: func(results []repoResult, start, end time.Time) [][]string {
+			var rows []contributorRow
+			for _, result := range results {
+				rows = append(rows, result.Bots...)
+			}
+			_, records := renderRows(columns, rows)
+			return records
+		},
+	}
+}
+
+// teamSection lists the totals of every team per repository and over all
+// repositories.
+func teamSection(opts options) section {
+	columns := teamColumns(opts)
+	header, _ := renderRows(columns, nil)
+	return section{
+		name:   "teams",
+		header: header,
+		records: func(results []repoResult, start, end time.Time) [][]string {
+			_, records := renderRows(columns, teamRows(results, opts.teams))
+			return records
+		},
+	}
+}
+
+// breakdownSection lists a line breakdown of every contributor row, or of
+// every team row with -teams-only.
+func breakdownSection(name, key string, breakdown func(r contributorRow) lineBreakdown, opts options) section {
+	who := "Contributor"
+	if opts.teamsOnly {
+		who = "Team"
+	}
+	return section{
+		name:   name,
+		header: []string{"Repository", who, key, "Additions", "Deletions", "StartDate", "EndDate"},
+		records: func(results []repoResult, start, end time.Time) [][]string {
+			var rows []contributorRow
+			if opts.teamsOnly {
+				rows = teamRows(results, opts.teams)
+			} else {
+				for _, result := range results {
+					rows = append(rows, result.Rows...)
+				}
+			}
+			var records [][]string
+			for _, r := range rows {
+				name := r.Contributor
+				if opts.teamsOnly {
+					name = r.Team
+				}
+				b := breakdown(r)
+				for _, k := range sortedKeys(b) {
+					records = append(records, []string{
+						r.Repository,
+						name,
+						k,
+						strconv.Itoa(b[k].Additions),
+						strconv.Itoa(b[k].Deletions),
+						start.Format("2006-01-02"),
+						end.Format("2006-01-02"),
+					})
+				}
+			}
+			return records
+		},
+	}
+}
+
+var issueSection = section{
+	name:   "issues",
+	header: []string{"Repository", "IssuesOpened", "IssuesClosed", "IssuesCommented", "MedianHoursToClose", "StartDate", "EndDate"},
+	records: func(results []repoResult, start, end time.Time) [][]string {
+		var records [][]string
+		for _, result := range results {
+			records = append(records, []string{
+				result.Repository,
+				strconv.Itoa(result.Issues.Opened),
+				strconv.Itoa(result.Issues.Closed),
+				strconv.Itoa(result.Issues.Commented),
+				formatHours(medianDuration(result.Issues.CloseTimes)),
+				start.Format("2006-01-02"),
+				end.Format("2006-01-02"),
+			})
+		}
+		return records
+	},
+}
+
+var doraSection = section{
+	name:   "dora",
+	header: []string{"Repository", "DeploymentSource", "Deployments", "DeploymentsPerWeek", "MedianHoursLeadTime", "ChangeFailureRate", "MedianHoursToRestore", "StartDate", "EndDate"},
+	records: func(results []repoResult, start, end time.Time) [][]string {
+		weeks := end.Sub(start).Hours() / (7 * 24)
+		var records [][]string
+		for _, result := range results {
+			d := result.DORA
+			failureRate := ""
+			if d.Deployments > 0 {
+				failureRate = strconv.FormatFloat(float64(d.Failures)/float64(d.Deployments), 'f', 2, 64)
+			}
+			records = append(records, []string{
+				result.Repository,
+				d.Source,
+				strconv.Itoa(d.Deployments),
+				strconv.FormatFloat(float64(d.Deployments)/weeks, 'f', 2, 64),
+				formatHours(medianDuration(d.LeadTimes)),
+				failureRate,
+				formatHours(medianDuration(d.RestoreTimes)),
+				start.Format("2006-01-02"),
+				end.Format("2006-01-02"),
+			})
+		}
+		return records
+	},
+}
+
+var weeklySection = section{
+	name:   "weekly",
+	header: []string{"Repository", "Week", "Additions", "Deletions", "Commits"},
+	records: func(results []repoResult, start, end time.Time) [][]string {
+		var records [][]string
+		for _, result := range results {
+			for _, w := range result.Stats.Weeks {
+				commits := ""
+				if w.Commits >= 0 {
+					commits = strconv.Itoa(w.Commits)
+				}
+				records = append(records, []string{
+					result.Repository,
+					w.Week.Format("2006-01-02"),
+					strconv.Itoa(w.Additions),
+					strconv.Itoa(w.Deletions),
+					commits,
+				})
+			}
+		}
+		return records
+	},
+}
+
+var dailySection = section{
+	name:   "daily",
+	header: []string{"Repository", "Date", "Commits"},
+	records: func(results []repoResult, start, end time.Time) [][]string {
+		var records [][]string
+		for _, result := range results {
+			for _, d := range result.Stats.Days {
+				records = append(records, []string{result.Repository, d.Date.Format("2006-01-02"), strconv.Itoa(d.Commits)})
+			}
+		}
+		return records
+	},
+}
+
+var participationSection = section{
+	name:   "participation",
+	header: []string{"Repository", "Week", "OwnerCommits", "AllCommits"},
+	records: func(results []repoResult, start, end time.Time) [][]string {
+		var records [][]string
+		for _, result := range results {
+			for _, p := range result.Stats.Participation {
+				records = append(records, []string{
+					result.Repository,
+					p.Week.Format("2006-01-02"),
+					strconv.Itoa(p.Owner),
+					strconv.Itoa(p.All),
+				})
+			}
+		}
+		return records
+	},
+}
+
+var punchCardSection = section{
+	name:   "punchcard",
+	header: []string{"Repository", "Day", "Hour", "Commits"},
+	records: func(results []repoResult, start, end time.Time) [][]string {
+		var records [][]string
+		for _, result := range results {
+			for _, p := range result.Stats.PunchCard {
+				records = append(records, []string{
+					result.Repository,
+					p.Day.String(),
+					strconv.Itoa(p.Hour),
+					strconv.Itoa(p.Commits),
+				})
+			}
+		}
+		return records
+	},
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// snapshot is the archived result of a run, together with its parameters.
+type snapshot struct {
+	ID      string
+	Created time.Time
+	Start   time.Time
+	End     time.Time
+	Output  string
+	Repos   []string
+	// Flags are the command line flags that affect the results.
+	Flags   map[string]string
+	Results []repoResult
+}
+
+// defaultArchiveDir returns the directory snapshots are stored in.
+func defaultArchiveDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "snapshots"
+	}
+	return filepath.Join(dir, "ghstats", "snapshots")
+}
+
+// saveSnapshot stores s in dir, named after its ID.
+func saveSnapshot(dir string, s snapshot) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, s.ID+".json"), data, 0o600)
+}
+
+// loadSnapshots reads every snapshot in dir, ordered by range and then by
+// when they were created.
+func loadSnapshots(dir string) ([]snapshot, error) {
+	files, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var snapshots []snapshot
+	for _, f := range files {
+		if f.IsDir() || filepath.Ext(f.Name()) != ".json" {
+			continue
+		}
+		s, err := loadSnapshot(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, s)
+	}
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		a, b := snapshots[i], snapshots[j]
+		if !a.Start.Equal(b.Start) {
+			return a.Start.Before(b.Start)
+		}
+		if !a.End.Equal(b.End) {
+			return a.End.Before(b.End)
+		}
+		return a.Created.Before(b.Created)
+	})
+	return snapshots, nil
+}
+
+func loadSnapshot(path string) (snapshot, error) {
+	var s snapshot
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// runHistory implements the history subcommand, which lists the archived
+// runs:
+//
+//	ghstats history [-archive DIR]
+func runHistory(args []string) error {
+	flags := flag.NewFlagSet("history", flag.ContinueOnError)
+	dir := flags.String("archive", defaultArchiveDir(), "snapshot archive `directory`")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	snapshots, err := loadSnapshots(*dir)
+	if err != nil {
+		return err
+	}
+
+	w := csv.NewWriter(os.Stdout)
+	w.Write([]string{"Snapshot", "Created", "StartDate", "EndDate", "Repositories", "Contributors", "Commits", "Flags"})
+	for _, s := range snapshots {
+		contributors := make(map[string]bool)
+		commits := 0
+		for _, result := range s.Results {
+			for _, r := range result.Rows {
+				contributors[r.Contributor] = true
+			}
+			commits += result.Commits
+		}
+		var flagList []string
+		for _, name := range sortedKeys(s.Flags) {
+			flagList = append(flagList, "-"+name+"="+s.Flags[name])
+		}
+		w.Write([]string{
+			s.ID,
+			s.Created.Format(time.RFC3339),
+			s.Start.Format("2006-01-02"),
+			s.End.Format("2006-01-02"),
+			strconv.Itoa(len(s.Results)),
+			strconv.Itoa(len(contributors)),
+			strconv.Itoa(commits),
+			strings.Join(flagList, " "),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// trendColumns are the metrics the trend subcommand can show.
+var trendColumns = metricColumns(slices.Concat(baseColumns, pullColumns, reviewColumns, excludedColumns, derivedColumns, issueColumns))
+
+// runTrend implements the trend subcommand, which shows the metrics of a
+// contributor, a repository or a contributor within a repository in every
+// archived run:
+//
+//	ghstats trend [-archive DIR] [-repo REPO] [-contributor LOGIN] [-metric NAME]...
+func runTrend(args []string) error {
+	flags := flag.NewFlagSet("trend", flag.ContinueOnError)
+	dir := flags.String("archive", defaultArchiveDir(), "snapshot archive `directory`")
+	repo := flags.String("repo", "", "only count the `repository` owner/name")
+	contributor := flags.String("contributor", "", "only count the `contributor`")
+	var metricNames stringList
+	flags.Var(&metricNames, "metric", "show the `metric`, a column of the contributor report (repeatable)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *repo == "" && *contributor == "" {
+		return fmt.Errorf("trend needs -repo, -contributor or both")
+	}
+	if len(metricNames) == 0 {
+		metricNames = stringList{"Additions", "Deletions", "Commits", "Net", "Churn"}
+	}
+	var metrics []column
+	for _, name := range metricNames {
+		i := slices.IndexFunc(trendColumns, func(c column) bool { return strings.EqualFold(c.name, name) })
+		if i < 0 {
+			return fmt.Errorf("unknown metric %q", name)
+		}
+		metrics = append(metrics, trendColumns[i])
+	}
+
+	snapshots, err := loadSnapshots(*dir)
+	if err != nil {
+		return err
+	}
+
+	w := csv.NewWriter(os.Stdout)
+	header := []string{"Snapshot", "StartDate", "EndDate"}
+	for _, m := range metrics {
+		header = append(header, m.name)
+	}
+	w.Write(header)
+	for _, s := range snapshots {
+		total, found := contributorRow{}, false
+		for _, result := range s.Results {
+			if *repo != "" && !strings.EqualFold(result.Repository, *repo) {
+				continue
+			}
+			found = true
+			total.RepoCommits += result.Commits
+			for _, r := range result.Rows {
+				if *contributor == "" || strings.EqualFold(r.Contributor, *contributor) {
+					total.add(r)
+				}
+			}
+		}
+		if !found {
+			continue
+		}
+		record := []string{s.ID, s.Start.Format("2006-01-02"), s.End.Format("2006-01-02")}
+		for _, m := range metrics {
+			record = append(record, m.value(total))
+		}
+		w.Write(record)
+	}
+	w.Flush()
+	return w.Error()
+}
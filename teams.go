@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+)
+
+const (
+	// noTeam collects the rows of contributors who are not on any team.
+	noTeam = "(no team)"
+	// allRepositories stands in for the repository of overall team rows.
+	allRepositories = "(all)"
+)
+
+// teamMap maps lowercased logins to the teams they are on.
+type teamMap map[string][]string
+
+type Team struct {
+	Slug string `json:"slug"`
+}
+
+type TeamMember struct {
+	Login string `json:"login"`
+}
+
+// loadTeams reads a team mapping file with one login and team per line:
+//
+//	login,team
+//	octocat,platform
+//	octocat,payments
+//
+// A header line starting with "login" and lines starting with # are skipped.
+func loadTeams(path string) (teamMap, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	teams := make(teamMap)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if strings.EqualFold(record[0], "login") {
+			continue
+		}
+		if len(record) != 2 || record[0] == "" || record[1] == "" {
+			line, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("%s:%d: expected login,team", path, line)
+		}
+		teams.add(record[0], record[1])
+	}
+	return teams, nil
+}
+
+// fetchOrgTeams adds the teams of a GitHub organization and their members to
+// teams.
+func fetchOrgTeams(client *http.Client, org, token string, teams teamMap) error {
+	var all []Team
+	url := fmt.Sprintf("%s/orgs/%s/teams?per_page=100", githubAPI, org)
+	for url != "" {
+		var page []Team
+		next, err := getJSON(client, url, token, &page)
+		if err != nil {
+			return err
+		}
+		all = append(all, page...)
+		url = next
+	}
+
+	for _, team := range all {
+		url := fmt.Sprintf("%s/orgs/%s/teams/%s/members?per_page=100", githubAPI, org, team.Slug)
+		for url != "" {
+			var page []TeamMember
+			next, err := getJSON(client, url, token, &page)
+			if err != nil {
+				return err
+			}
+			for _, member := range page {
+				teams.add(member.Login, team.Slug)
+			}
+			url = next
+		}
+	}
+	return nil
+}
+
+func (m teamMap) add(login, team string) {
+	login = strings.ToLower(login)
+	if !slices.Contains(m[login], team) {
+		m[login] = append(m[login], team)
+	}
+}
+
+// teamsOf returns the teams a row counts towards. A team from the identity
+// map takes precedence; otherwise the row counts towards every team of its
+// contributor and original logins.
+func (m teamMap) teamsOf(r contributorRow) []string {
+	if r.Team != "" {
+		return []string{r.Team}
+	}
+	var teams []string
+	for _, login := range append([]string{r.Contributor}, r.Logins...) {
+		for _, team := range m[strings.ToLower(login)] {
+			if !slices.Contains(teams, team) {
+				teams = append(teams, team)
+			}
+		}
+	}
+	if len(teams) == 0 {
+		return []string{noTeam}
+	}
+	return teams
+}
+
+// teamRows sums up the contributor rows per team, first for every repository
+// and then over all repositories. The Logins of a team row list its members;
+// their commit emails are left out.
+func teamRows(results []repoResult, teams teamMap) []contributorRow {
+	addTo := func(totals map[string]*contributorRow, repo, team string, r contributorRow) {
+		t, ok := totals[team]
+		if !ok {
+			t = &contributorRow{Repository: repo, Team: team, Start: r.Start, End: r.End, RepoCommits: r.RepoCommits}
+			totals[team] = t
+		}
+		members := t.Logins
+		t.add(r)
+		t.Logins, t.Emails = members, nil
+		if !slices.Contains(t.Logins, r.Contributor) {
+			t.Logins = append(t.Logins, r.Contributor)
+		}
+	}
+
+	var rows []contributorRow
+	overall := make(map[string]*contributorRow)
+	allCommits := 0
+	for _, result := range results {
+		allCommits += result.Commits
+		perRepo := make(map[string]*contributorRow)
+		for _, r := range result.Rows {
+			for _, team := range teams.teamsOf(r) {
+				addTo(perRepo, result.Repository, team, r)
+				addTo(overall, allRepositories, team, r)
+			}
+		}
+		for _, team := range sortedKeys(perRepo) {
+			rows = append(rows, *perRepo[team])
+		}
+	}
+	for _, team := range sortedKeys(overall) {
+		overall[team].RepoCommits = allCommits
+		rows = append(rows, *overall[team])
+	}
+	for i := range rows {
+		slices.Sort(rows[i].Logins)
+	}
+	return rows
+}
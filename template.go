@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// templateData is the model an output template is executed with.
+type templateData struct {
+	Start     time.Time
+	End       time.Time
+	Generated time.Time
+	// TimeZone names the zone of the times, from -tz.
+	TimeZone string
+	// Flags are the command line flags that affect the results.
+	Flags        map[string]string
+	Repositories []repoResult
+	Contributors []contributorRow
+	Bots         []contributorRow
+	// Teams holds the team rows, when teams are given.
+	Teams []contributorRow
+	// Columns are the columns of the contributor report.
+	Columns []string
+}
+
+// allColumns are every column a contributor row can be rendered with.
+var allColumns = slices.Concat(baseColumns, pullColumns, reviewColumns, identityColumns, excludedColumns, derivedColumns, issueColumns)
+
+var templateFuncs = template.FuncMap{
+	// column renders a column of a row as in the contributor report, e.g.
+	// {{column . "MedianHoursToMerge"}}.
+	"column": func(r contributorRow, name string) (string, error) {
+		i := slices.IndexFunc(allColumns, func(c column) bool { return normalizeColumn(c.name) == normalizeColumn(name) })
+		if i < 0 {
+			return "", fmt.Errorf("unknown column %q", name)
+		}
+		return allColumns[i].value(r), nil
+	},
+	"date":    func(t time.Time) string { return t.Format("2006-01-02") },
+	"week":    func(unix int64) time.Time { return time.Unix(unix, 0).UTC() },
+	"hours":   func(d time.Duration) string { return formatHours(d) },
+	"join":    strings.Join,
+	"lower":   strings.ToLower,
+	"upper":   strings.ToUpper,
+	"replace": strings.ReplaceAll,
+	"add":     func(a, b int) int { return a + b },
+	"sub":     func(a, b int) int { return a - b },
+}
+
+// parseTemplate reads an output template.
+func parseTemplate(path string) (*template.Template, error) {
+	return template.New(filepath.Base(path)).Funcs(templateFuncs).ParseFiles(path)
+}
+
+// newTemplateData builds the model of templates and JSON output.
+func newTemplateData(results []repoResult, start, end time.Time, opts options) templateData {
+	data := templateData{
+		Start:        start,
+		End:          end,
+		Generated:    time.Now().In(opts.location),
+		TimeZone:     opts.location.String(),
+		Flags:        resultFlags(),
+		Repositories: results,
+	}
+	if opts.teamsOnly {
+		// As in the other outputs, no individual is named.
+		data.Repositories = make([]repoResult, len(results))
+		for i, result := range results {
+			result.Rows, result.Bots = nil, nil
+			if result.Baseline != nil {
+				baseline := *result.Baseline
+				baseline.Rows, baseline.Bots = nil, nil
+				result.Baseline = &baseline
+			}
+			data.Repositories[i] = result
+		}
+	} else {
+		for _, result := range results {
+			data.Contributors = append(data.Contributors, result.Rows...)
+			data.Bots = append(data.Bots, result.Bots...)
+		}
+	}
+	if opts.teamRollup() {
+		data.Teams = teamRows(results, opts.teams)
+		if opts.teamsOnly {
+			for i := range data.Teams {
+				data.Teams[i].Logins, data.Teams[i].Emails = nil, nil
+			}
+		}
+	}
+	data.Columns, _ = renderRows(contributorColumns(opts), nil)
+	return data
+}
+
+// writeTemplate executes the template with the results and writes the
+// output to path.
+func writeTemplate(path string, tmpl *template.Template, results []repoResult, start, end time.Time, opts options) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := tmpl.Execute(f, newTemplateData(results, start, end, opts)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTemplateDataTeamsOnly(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 1, 31, 23, 59, 59, 0, time.UTC)
+	alice := contributorRow{
+		Repository:  "owner/repo",
+		Contributor: "alice",
+		Logins:      []string{"alice", "alice-work"},
+		Emails:      []string{"alice@corp.com"},
+		Commits:     3,
+		Start:       start,
+		End:         end,
+	}
+	bot := contributorRow{Repository: "owner/repo", Contributor: "ci-bot[bot]", Bot: true, Start: start, End: end}
+	result := repoResult{Repository: "owner/repo", Rows: []contributorRow{alice}, Bots: []contributorRow{bot}, Commits: 3}
+	baseline := result
+	result.Baseline = &baseline
+	opts := options{
+		location:  time.UTC,
+		teamsOnly: true,
+		teams:     teamMap{"alice": {"platform"}},
+	}
+
+	data := newTemplateData([]repoResult{result}, start, end, opts)
+	if len(data.Teams) == 0 {
+		t.Fatal("no team rows")
+	}
+	model, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"alice", "alice-work", "alice@corp.com", "ci-bot"} {
+		if strings.Contains(string(model), name) {
+			t.Errorf("the model names %q:\n%s", name, model)
+		}
+	}
+	if !strings.Contains(string(model), "platform") {
+		t.Errorf("the model has no team platform:\n%s", model)
+	}
+}
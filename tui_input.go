@@ -28,6 +28,9 @@ var (
 			Foreground(lipgloss.Color("#FAFAFA")).
 			Background(lipgloss.Color("#7D56F4")).
 			Padding(0, 1)
+
+	errorStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#FF5F87"))
 )
 
 type inputState int
@@ -56,26 +59,39 @@ type inputModel struct {
 	outputFormat string
 	outputPath   string
 	reposPath    string
+
+	// from and to are the range the date expressions resolve to.
+	from time.Time
+	to   time.Time
+	// err is the error in the last value entered, which is asked for again.
+	err error
+
+	// outputs were given with -out, so the output path is not asked for.
+	outputs []string
+	// loc is the time zone of the dates, from -tz.
+	loc *time.Location
 }
 
-func getDefaultDates() (string, string) {
-	now := time.Now()
+func getDefaultDates(loc *time.Location) (string, string) {
+	now := time.Now().In(loc)
 	startOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
 	return startOfMonth.Format("2006-01-02"), now.Format("2006-01-02")
 }
 
-func newInputModel() inputModel {
+func newInputModel(outputs []string, loc *time.Location) inputModel {
 	m := inputModel{
-		state:  inputStart,
-		start:  textinput.New(),
-		end:    textinput.New(),
-		token:  textinput.New(),
-		output: textinput.New(),
-		repos:  textinput.New(),
+		state:   inputStart,
+		start:   textinput.New(),
+		end:     textinput.New(),
+		token:   textinput.New(),
+		output:  textinput.New(),
+		repos:   textinput.New(),
+		outputs: outputs,
+		loc:     loc,
 	}
-	m.start.Placeholder = "YYYY-MM-DD"
+	m.start.Placeholder = "YYYY-MM-DD, 2024-Q3, last-month, -30d..."
 	m.start.Prompt = "Start Date: "
-	m.end.Placeholder = "YYYY-MM-DD"
+	m.end.Placeholder = "YYYY-MM-DD, 2024-Q3, this-month, today..."
 	m.end.Prompt = "End Date: "
 	m.token.Placeholder = "github_pat_..."
 	m.token.Prompt = "GitHub Token: "
@@ -107,37 +123,41 @@ func (m inputModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			case inputStart:
 				m.startDate = m.start.Value()
 				if m.startDate == "" {
-					defaultStart, _ := getDefaultDates()
+					defaultStart, _ := getDefaultDates(m.loc)
 					m.startDate = defaultStart
 				}
+				if _, _, m.err = dateRange(m.startDate, time.Now().In(m.loc)); m.err != nil {
+					return m, nil
+				}
 				m.state = inputEnd
 				m.start.Blur()
 				m.end.Focus()
 			case inputEnd:
+				// An empty end date is resolved from the start date.
 				m.endDate = m.end.Value()
-				if m.endDate == "" {
-					_, defaultEnd := getDefaultDates()
-					m.endDate = defaultEnd
+				if m.from, m.to, m.err = parseDates(m.startDate, m.endDate, time.Now().In(m.loc)); m.err != nil {
+					return m, nil
 				}
 				if os.Getenv("GITHUB_TOKEN") == "" {
 					m.state = inputToken
 					m.end.Blur()
 					m.token.Focus()
 				} else {
-					m.state = inputOutput
 					m.end.Blur()
-					m.output.Focus()
+					m = m.toOutput()
 				}
 			case inputToken:
 				m.githubToken = m.token.Value()
-				m.state = inputOutput
 				m.token.Blur()
-				m.output.Focus()
+				m = m.toOutput()
 			case inputOutput:
 				m.outputPath = m.output.Value()
 				if m.outputPath == "" {
 					m.outputPath = "output.csv"
 				}
+				if _, m.err = parseOutput(m.outputPath); m.err != nil {
+					return m, nil
+				}
 				m.state = inputRepos
 				m.output.Blur()
 				m.repos.Focus()
@@ -170,19 +190,36 @@ func (m inputModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// toOutput moves on to the output path, which is skipped when outputs were
+// given with -out.
+func (m inputModel) toOutput() inputModel {
+	if len(m.outputs) > 0 {
+		m.state = inputRepos
+		m.repos.Focus()
+		return m
+	}
+	m.state = inputOutput
+	m.output.Focus()
+	return m
+}
+
 func (m inputModel) View() string {
 	if m.state == inputDone {
 		var sb strings.Builder
-		defaultStart, defaultEnd := getDefaultDates()
 
 		sb.WriteString(titleStyle.Render("✨ Inputs Received") + "\n\n")
 
-		sb.WriteString(inputLabelStyle.Render("Start Date: ") + valueStyle.Render(getValueOrDefault(m.startDate, defaultStart+" (start of month)")) + "\n")
-		sb.WriteString(inputLabelStyle.Render("End Date: ") + valueStyle.Render(getValueOrDefault(m.endDate, defaultEnd+" (today)")) + "\n")
+		sb.WriteString(inputLabelStyle.Render("Start Date: ") + valueStyle.Render(resolvedDate(m.from, m.startDate)) + "\n")
+		sb.WriteString(inputLabelStyle.Render("End Date: ") + valueStyle.Render(resolvedDate(m.to, getValueOrDefault(m.endDate, "default"))) + "\n")
+		sb.WriteString(inputLabelStyle.Render("Time Zone: ") + valueStyle.Render(m.loc.String()) + "\n")
 		if m.githubToken != "" {
 			sb.WriteString(inputLabelStyle.Render("GitHub Token: ") + valueStyle.Render("[provided]") + "\n")
 		}
-		sb.WriteString(inputLabelStyle.Render("Output File: ") + valueStyle.Render(getValueOrDefault(m.outputPath, "output.csv")) + "\n")
+		if len(m.outputs) > 0 {
+			sb.WriteString(inputLabelStyle.Render("Output Files: ") + valueStyle.Render(strings.Join(m.outputs, ", ")) + "\n")
+		} else {
+			sb.WriteString(inputLabelStyle.Render("Output File: ") + valueStyle.Render(getValueOrDefault(m.outputPath, "output.csv")) + "\n")
+		}
 		sb.WriteString(inputLabelStyle.Render("Repositories File: ") + valueStyle.Render(getValueOrDefault(m.reposPath, "repos.txt")) + "\n\n")
 
 		sb.WriteString(highlightStyle.Render("Starting processing..."))
@@ -203,10 +240,23 @@ func (m inputModel) View() string {
 	case inputRepos:
 		s += m.repos.View()
 	}
+	if m.err != nil {
+		s += "\n\n" + errorStyle.Render(m.err.Error())
+	}
 	s += "\n\n" + lipgloss.NewStyle().Faint(true).Render("(Press Enter for default value)")
 	return s
 }
 
+// resolvedDate renders a date of the range, with the expression it was
+// given as if that was not the date itself.
+func resolvedDate(t time.Time, expr string) string {
+	date := t.Format("2006-01-02")
+	if expr == "" || expr == date {
+		return date
+	}
+	return date + " (" + expr + ")"
+}
+
 func getValueOrDefault(value, defaultValue string) string {
 	if value == "" {
 		return defaultValue